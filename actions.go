@@ -0,0 +1,54 @@
+package main
+
+// Actions the entities respond to, bound to controls in assets/bindings.json.
+const (
+	ACTION_THROTTLE       = "throttle"
+	ACTION_STABILITY      = "stability"
+	ACTION_POWER_SPLIT    = "power.split"
+	ACTION_BODY_ROTATE    = "body.rotate"
+	ACTION_JET_ROTATE     = "jet.rotate"
+	ACTION_ENGINE_SPINUP  = "engine.spinup"
+	ACTION_ENGINE_RESTART = "engine.restart"
+	ACTION_NUDGE_LEFT     = "debug.nudgeLeft"
+	ACTION_NUDGE_RIGHT    = "debug.nudgeRight"
+	ACTION_NUDGE_UP       = "debug.nudgeUp"
+	ACTION_NUDGE_DOWN     = "debug.nudgeDown"
+
+	ACTION_RADIO_TUNE      = "radio.tune"
+	ACTION_RADIO_FINE_TUNE = "radio.fineTune"
+	ACTION_RADIO_VOLUME    = "radio.volume"
+	ACTION_RADIO_SQUELCH   = "radio.squelch"
+	ACTION_RADIO_MODE      = "radio.mode"
+	ACTION_RADIO_RDF       = "radio.rdf"
+	ACTION_RADIO_BAND      = "radio.band"
+	ACTION_RADIO_SEEK_UP   = "radio.seekUp"
+	ACTION_RADIO_SEEK_DOWN = "radio.seekDown"
+	// Followed by the memory channel number, 1-4
+	ACTION_RADIO_MEMORY = "radio.memory."
+	// Followed by the RadioMessage
+	ACTION_RADIO_TRANSMIT  = "radio.transmit."
+	ACTION_RADIO_LOG_OLDER = "radio.log.older"
+	ACTION_RADIO_LOG_NEWER = "radio.log.newer"
+	ACTION_RADIO_LOG_LAST  = "radio.log.latest"
+)
+
+// LEDs the entities light, named as in the controller profiles.
+const (
+	LED_ENGINE     = "left.play"
+	LED_STRESS     = "right.sync"
+	LED_TRANSMIT_1 = "left.pad.1"
+	LED_TRANSMIT_2 = "left.pad.2"
+	LED_TRANSMIT_3 = "left.pad.3"
+	LED_TRANSMIT_4 = "left.pad.4"
+)
+
+// Meters the entities fill, for controllers that have them.
+const (
+	METER_SIGNAL = "left.meter"
+	METER_STRESS = "right.meter"
+)
+
+// Displays the entities write to, on OSC surfaces.
+const (
+	DISPLAY_RADIO = "radio"
+)
@@ -11,9 +11,15 @@
   <image width="16" height="16" source="til_landing.png"/>
  </tile>
  <tile id="3">
+  <properties>
+   <property name="terrain" type="bool" value="true"/>
+  </properties>
   <image width="16" height="16" source="til_mountain_2.png"/>
  </tile>
  <tile id="4">
+  <properties>
+   <property name="terrain" type="bool" value="true"/>
+  </properties>
   <image width="16" height="16" source="til_mountain.png"/>
  </tile>
  <tile id="5">
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/faiface/pixel"
+	"github.com/mateusz/carryall/engine/sid"
+	"github.com/mateusz/carryall/piksele"
+)
+
+const (
+	BEACON_DEFAULT_TONE = 800.0 // Hz
+	BEACON_WPM          = 12.0
+)
+
+// Beacon is a fixed radio source keying out its identifier in Morse, or holding a continuous tone if it has none.
+type Beacon struct {
+	freq     float64
+	location pixel.Vec
+	signal   sid.SignalSource
+}
+
+func NewBeacon(freq float64, location pixel.Vec, ident string, tone float64) *Beacon {
+	b := &Beacon{
+		freq:     freq,
+		location: location,
+	}
+
+	osc := sid.NewSine(tone, 1)
+	if ident == "" {
+		b.signal = osc
+	} else {
+		b.signal = sid.NewMorse(osc, ident, BEACON_WPM)
+	}
+	return b
+}
+
+// LoadBeacons places a beacon for each "beacon" object on the map. The object name is looked up
+// in the radio frequency plan, its "ident" and "tone" properties shape the signal.
+func LoadBeacons(w *piksele.World, r *Radio) []*Beacon {
+	beacons := make([]*Beacon, 0)
+	for _, o := range w.ObjectsOfType("beacon") {
+		if o.Name == "" {
+			fmt.Printf("Error loading beacons: beacon %d has no name\n", o.ID)
+			os.Exit(2)
+		}
+		tone := o.Properties.GetFloat("tone")
+		if tone == 0.0 {
+			tone = BEACON_DEFAULT_TONE
+		}
+		beacons = append(beacons, NewBeacon(r.SourceFreq(o.Name), w.ObjectToVec(o), o.Properties.GetString("ident"), tone))
+	}
+	return beacons
+}
+
+func (s *Beacon) GetFreq() float64 {
+	return s.freq
+}
+
+func (s *Beacon) GetLocation() pixel.Vec {
+	return s.location
+}
+
+func (s *Beacon) GetSignal() sid.SignalSource {
+	return s.signal
+}
+
+func (s *Beacon) GetFiller() sid.SignalSource {
+	return s.signal
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/faiface/pixel/text"
+	"github.com/mateusz/carryall/engine/input"
+	"golang.org/x/image/font/basicfont"
+)
+
+// Faders that moved less than this while calibrating are left as they were
+const CALIBRATION_MIN_TRAVEL = 0.5
+
+// calibrationScreen captures where the controller's faders and knobs really end, and where the
+// centred ones rest. F2 opens it, Enter saves into the controller profile and F2 again gives up.
+type calibrationScreen struct {
+	active  bool
+	profile *input.Profile
+	min     map[string]float64
+	max     map[string]float64
+	txt     *text.Text
+}
+
+func newCalibrationScreen() *calibrationScreen {
+	return &calibrationScreen{
+		txt: text.New(pixel.ZV, text.NewAtlas(basicfont.Face7x13, text.ASCII)),
+	}
+}
+
+// SetProfile is the plugged in controller's profile, nil when there isn't one to calibrate.
+func (c *calibrationScreen) SetProfile(p *input.Profile) {
+	c.profile = p
+	if p == nil {
+		c.active = false
+	}
+}
+
+func (c *calibrationScreen) Input(win *pixelgl.Window, actions *input.Actions) {
+	if win.JustPressed(pixelgl.KeyF2) {
+		if c.profile == nil {
+			fmt.Printf("No controller to calibrate\n")
+			return
+		}
+		c.active = !c.active
+		c.min = make(map[string]float64)
+		c.max = make(map[string]float64)
+	}
+	if !c.active {
+		return
+	}
+
+	faders := actions.Faders()
+	for _, f := range faders {
+		if !f.Touched {
+			continue
+		}
+		min, ok := c.min[f.Control]
+		if !ok || f.Raw < min {
+			c.min[f.Control] = f.Raw
+		}
+		max, ok := c.max[f.Control]
+		if !ok || f.Raw > max {
+			c.max[f.Control] = f.Raw
+		}
+	}
+
+	if !win.JustPressed(pixelgl.KeyEnter) {
+		return
+	}
+	for _, f := range faders {
+		if c.max[f.Control]-c.min[f.Control] < CALIBRATION_MIN_TRAVEL {
+			continue
+		}
+		cal := input.AxisCalibration{Min: c.min[f.Control], Max: c.max[f.Control]}
+		if f.Centred {
+			centre := f.Raw
+			cal.Centre = &centre
+		}
+		c.profile.Calibration[f.Control] = cal
+	}
+	err := c.profile.Save()
+	if err != nil {
+		fmt.Printf("Error saving calibration: %s\n", err)
+	} else {
+		fmt.Printf("Saved calibration for %s\n", c.profile.Name)
+	}
+	c.active = false
+}
+
+func (c *calibrationScreen) Draw(t pixel.Target, actions *input.Actions, at pixel.Vec) {
+	if !c.active {
+		return
+	}
+
+	c.txt.Clear()
+	fmt.Fprintf(c.txt, "CALIBRATING %s\n", c.profile.Name)
+	fmt.Fprintf(c.txt, "Move every fader and knob end to end, then leave the centred ones at rest.\n")
+	fmt.Fprintf(c.txt, "Enter saves, F2 cancels.\n\n")
+	for _, f := range actions.Faders() {
+		travel := "not moved"
+		min, ok := c.min[f.Control]
+		if ok {
+			travel = fmt.Sprintf("%.3f - %.3f", min, c.max[f.Control])
+			if c.max[f.Control]-min < CALIBRATION_MIN_TRAVEL {
+				travel += " (keep going)"
+			}
+		}
+		centred := ""
+		if f.Centred {
+			centred = fmt.Sprintf("rests at %.3f", f.Raw)
+		}
+		fmt.Fprintf(c.txt, "%-14s %5.3f  %-26s %s\n", f.Control, math.Max(f.Raw, 0.0), travel, centred)
+	}
+	c.txt.Draw(t, pixel.IM.Moved(at))
+}
@@ -6,13 +6,9 @@ import (
 	"time"
 
 	"github.com/faiface/pixel"
-	"github.com/faiface/pixel/pixelgl"
-	engine "github.com/mateusz/carryall/engine/entities"
+	"github.com/mateusz/carryall/engine/input"
 	"github.com/mateusz/carryall/engine/sid"
 	"github.com/mateusz/carryall/piksele"
-	"gitlab.com/gomidi/midi"
-	"gitlab.com/gomidi/midi/midimessage/channel"
-	"gitlab.com/gomidi/midi/writer"
 )
 
 const SID_CHAN_ENGINE = "engine"
@@ -38,6 +34,7 @@ type Carryall struct {
 
 	// Audio
 	engineSound *sid.Vibrato
+	creaking    *sid.Granular
 
 	// State
 	engineSpinup        float64
@@ -50,15 +47,12 @@ type Carryall struct {
 	destroyingStart     time.Time
 	destroyingAudioDone bool
 	accelerationStress  float64
-	engineSpinupStart   time.Time
-	engineSpinupDone    bool
-	stressLightOn       bool
 	atmoPressure        float64
 
 	// Input counters
-	leftPanTicks  int64
+	leftPanTicks  float64
 	leftBalVal    float64 // Absolute value [0.0,1.0]
-	rightPanTicks int64
+	rightPanTicks float64
 	rightBalVal   float64 // Absolute value [0.0,1.0]
 	middleBalVal  float64 // Absolute value [0.0,1.0]
 	playIsHeld    bool
@@ -149,7 +143,7 @@ func (s Carryall) Draw(onto pixel.Target) {
 		onto,
 		engineTransform,
 	)
-	if s.rightBalVal > 0.55 {
+	if s.currentEnginePower > 0.0 {
 		scale := s.currentEnginePower / s.enginePower
 		s.engineJet.Spriteset.Sprites[s.engineJet.SpriteID+frame].DrawColorMask(
 			onto,
@@ -157,7 +151,7 @@ func (s Carryall) Draw(onto pixel.Target) {
 			color.Alpha{A: 127},
 		)
 	}
-	if s.rightBalVal < 0.45 {
+	if s.currentEnginePower < 0.0 {
 		scale := s.currentEnginePower / s.enginePower
 		s.engineJet.Spriteset.Sprites[s.engineJet.SpriteID+frame].DrawColorMask(
 			onto,
@@ -187,9 +181,6 @@ func (s *Carryall) Step(dt float64) {
 	factor := 0.02
 
 	if s.playIsHeld && s.engineSpinup < 1.0 {
-		if s.engineSpinupStart.Before(startTime) {
-			s.engineSpinupStart = time.Now()
-		}
 		s.engineSpinup += 0.25 * factor
 		if s.engineSpinup > 1.0 {
 			s.engineSpinup = 1.0
@@ -199,7 +190,7 @@ func (s *Carryall) Step(dt float64) {
 		s.engineSpinup -= 0.15 * factor
 	}
 
-	s.bodyRotation += -factor * 3.14 * float64(s.leftPanTicks) * s.bodyRotationSpeed
+	s.bodyRotation += -factor * 3.14 * s.leftPanTicks * s.bodyRotationSpeed
 	if s.bodyRotation < -s.bodyRotationLimit {
 		s.bodyRotation = -s.bodyRotationLimit
 	}
@@ -208,7 +199,7 @@ func (s *Carryall) Step(dt float64) {
 	}
 
 	// Jets are drawn vertical, but positioned horizontal
-	s.engineRotation += -factor * 3.14 * float64(s.rightPanTicks) * s.engineRotationSpeed
+	s.engineRotation += -factor * 3.14 * s.rightPanTicks * s.engineRotationSpeed
 	jetRangeMin := -math.Pi/2.0 - s.engineRotationLimit
 	jetRangeMax := -math.Pi/2.0 + s.engineRotationLimit
 	if s.engineRotation < jetRangeMin {
@@ -270,120 +261,53 @@ func (s *Carryall) Step(dt float64) {
 	}
 }
 
-func (s *Carryall) Input(win *pixelgl.Window, ref pixel.Matrix) {
+func (s *Carryall) Input(actions *input.Actions) {
 	if s.destroyingStart.After(startTime) {
 		return
 	}
 
-	if win.Pressed(pixelgl.KeyLeft) {
+	if actions.Held(ACTION_NUDGE_LEFT) {
 		s.velocity = s.velocity.Add(pixel.Vec{X: -10.0})
 	}
-	if win.Pressed(pixelgl.KeyRight) {
+	if actions.Held(ACTION_NUDGE_RIGHT) {
 		s.velocity = s.velocity.Add(pixel.Vec{X: 10.0})
 	}
-	if win.Pressed(pixelgl.KeyUp) {
+	if actions.Held(ACTION_NUDGE_UP) {
 		s.velocity = s.velocity.Add(pixel.Vec{Y: 10.0})
 	}
-	if win.Pressed(pixelgl.KeyDown) {
+	if actions.Held(ACTION_NUDGE_DOWN) {
 		s.velocity = s.velocity.Add(pixel.Vec{Y: -10.0})
 	}
-}
 
-func (s *Carryall) MidiInput(msgs []midi.Message) {
-	if s.destroyingStart.After(startTime) {
-		return
+	s.playIsHeld = actions.Held(ACTION_ENGINE_SPINUP)
+	if actions.Pressed(ACTION_ENGINE_RESTART) {
+		s.engineSpinup = 0.9
 	}
 
-	s.leftPanTicks = 0
-	s.rightPanTicks = 0
-	for _, m := range msgs {
-		non, ok := m.(channel.NoteOn)
-		if ok {
-			if non.Channel() == engine.MIDI_CHAN_LEFT && non.Key() == engine.MIDI_KEY_PLAY {
-				s.playIsHeld = true
-			}
-			if non.Channel() == engine.MIDI_CHAN_LEFT && non.Key() == engine.MIDI_KEY_SYNC {
-				s.engineSpinupStart = time.Now()
-				s.engineSpinupDone = false
-				s.engineSpinup = 0.9
-			}
-		}
-		noff, ok := m.(channel.NoteOff)
-		if ok {
-			if noff.Channel() == engine.MIDI_CHAN_LEFT && noff.Key() == engine.MIDI_KEY_PLAY {
-				s.playIsHeld = false
-			}
-		}
-
-		cc, ok := m.(channel.ControlChange)
-		if ok {
-			if cc.Channel() == engine.MIDI_CHAN_LEFT && (cc.Controller() == engine.MIDI_CTRL_RIM || cc.Controller() == engine.MIDI_CTRL_PAN) {
-				if cc.Value() == engine.MIDI_VAL_PAN_CCW {
-					s.leftPanTicks--
-				} else if cc.Value() == engine.MIDI_VAL_PAN_CW {
-					s.leftPanTicks++
-				}
-			}
-
-			if cc.Channel() == engine.MIDI_CHAN_LEFT && cc.Controller() == engine.MIDI_CTRL_BALANCE_MSB {
-				// Scaled to 0.0-1.0
-				s.leftBalVal = float64(cc.Value()) / 127
-			}
-
-			if cc.Channel() == engine.MIDI_CHAN_RIGHT && (cc.Controller() == engine.MIDI_CTRL_RIM || cc.Controller() == engine.MIDI_CTRL_PAN) {
-				if cc.Value() == engine.MIDI_VAL_PAN_CCW {
-					s.rightPanTicks--
-				} else if cc.Value() == engine.MIDI_VAL_PAN_CW {
-					s.rightPanTicks++
-				}
-			}
-
-			if cc.Channel() == engine.MIDI_CHAN_RIGHT && cc.Controller() == engine.MIDI_CTRL_BALANCE_MSB {
-				// Scaled to 0.0-1.0
-				s.rightBalVal = float64(cc.Value()) / 127
-			}
-
-			if cc.Channel() == engine.MIDI_CHAN_MIDDLE && cc.Controller() == engine.MIDI_CTRL_BANK_SELECT_MSB {
-				// Scaled to 0.0-1.0
-				s.middleBalVal = float64(cc.Value()) / 127
-			}
+	s.leftPanTicks = actions.Delta(ACTION_BODY_ROTATE)
+	s.rightPanTicks = actions.Delta(ACTION_JET_ROTATE)
 
-		}
+	// All scaled to 0.0-1.0
+	if actions.Moved(ACTION_STABILITY) {
+		s.leftBalVal = actions.Axis(ACTION_STABILITY)
+	}
+	if actions.Moved(ACTION_THROTTLE) {
+		s.rightBalVal = actions.Axis(ACTION_THROTTLE)
+	}
+	if actions.Moved(ACTION_POWER_SPLIT) {
+		s.middleBalVal = actions.Axis(ACTION_POWER_SPLIT)
 	}
 }
 
-func (s *Carryall) MidiOutput(wr *writer.Writer) {
-	wr.SetChannel(engine.MIDI_CHAN_RIGHT)
-	if s.accelerationStress >= 2.8 && !s.stressLightOn {
-		s.stressLightOn = true
-		writer.NoteOn(wr, engine.MIDI_KEY_SYNC, 0x7F)
-	}
-	if s.accelerationStress < 2.8 && s.stressLightOn {
-		s.stressLightOn = false
-		writer.NoteOff(wr, engine.MIDI_KEY_SYNC)
-	}
-
-	wr.SetChannel(engine.MIDI_CHAN_LEFT)
-	if !s.engineSpinupDone && s.engineSpinupStart.After(startTime) {
-		spinupFrame := uint32(time.Since(s.engineSpinupStart) / (time.Millisecond * 250.0))
-		if s.engineSpinup == 1.0 {
-			s.engineSpinupStart = time.Time{}
-			s.engineSpinupDone = true
-			wr.SetChannel(engine.MIDI_CHAN_LEFT)
-			writer.NoteOn(wr, engine.MIDI_KEY_PLAY, 0x7F)
-		} else if !s.playIsHeld {
-			s.engineSpinupStart = time.Time{}
-			wr.SetChannel(engine.MIDI_CHAN_LEFT)
-			writer.NoteOff(wr, engine.MIDI_KEY_PLAY)
-		} else if spinupFrame%2 == 1 {
-			wr.SetChannel(engine.MIDI_CHAN_LEFT)
-			writer.NoteOn(wr, engine.MIDI_KEY_PLAY, 0x7F)
-		} else {
-			wr.SetChannel(engine.MIDI_CHAN_LEFT)
-			writer.NoteOff(wr, engine.MIDI_KEY_PLAY)
-		}
-	}
+func (s *Carryall) MidiOutput(out *input.Output) {
+	out.Light(LED_STRESS, s.accelerationStress >= 2.8)
+	out.Level(METER_STRESS, s.accelerationStress/3.0)
 
+	if s.engineSpinup == 1.0 {
+		out.Light(LED_ENGINE, true)
+	} else if s.playIsHeld {
+		out.Blink(LED_ENGINE, 2.0)
+	}
 }
 
 func (s *Carryall) GetChannels() map[string]*sid.Channel {
@@ -407,9 +331,13 @@ func (s *Carryall) SetupChannels(onto *sid.Sid) {
 	onto.SetSource(SID_CHAN_STRESS_ALERT, sid.NewMp3("assets/stress_alert3.mp3", true))
 	onto.Pause(SID_CHAN_STRESS_ALERT)
 
-	onto.SetSource(SID_CHAN_ENGINE_WHOOSH, sid.NewPinkNoise(5))
+	onto.SetSource(SID_CHAN_ENGINE_WHOOSH, sid.NewPinkNoise(5, time.Now().UnixNano()))
 
-	onto.SetSource(SID_CHAN_CREAKING, sid.NewMp3("assets/submarine_breaking3.mp3", true))
+	s.creaking = sid.NewGranular([][]float32{
+		sid.LoadMp3Samples("assets/submarine_breaking2.mp3"),
+		sid.LoadMp3Samples("assets/submarine_breaking3.mp3"),
+	}, time.Now().UnixNano())
+	onto.SetSource(SID_CHAN_CREAKING, s.creaking)
 
 	onto.SetSource(SID_CHAN_EXPLOSION, sid.NewMp3("assets/explosion2.mp3", false))
 	onto.Pause(SID_CHAN_EXPLOSION)
@@ -457,6 +385,10 @@ func (s *Carryall) MakeNoise(onto *sid.Sid) {
 			stressLevel = 1.0
 		}
 		onto.SetVolume(SID_CHAN_CREAKING, math.Sqrt(stressLevel)*0.25+0.05)
+		// More load - denser, shorter and more strained grains
+		s.creaking.SetDensity(5.0 + stressLevel*35.0)
+		s.creaking.SetGrainLength(0.25 - stressLevel*0.17)
+		s.creaking.SetPitchSpread(1.0 + stressLevel*6.0)
 	} else {
 		onto.Pause(SID_CHAN_CREAKING)
 	}
@@ -1,42 +1,18 @@
 package engine
 
 import (
-	"github.com/faiface/pixel"
-	"github.com/faiface/pixel/pixelgl"
-	"gitlab.com/gomidi/midi"
+	"github.com/mateusz/carryall/engine/input"
 )
 
 type Inputtable interface {
-	Input(inputSource *pixelgl.Window, referenceFrame pixel.Matrix)
-	MidiInput(msgs []midi.Message)
+	Input(actions *input.Actions)
 }
 
-func (e Entities) Input(inputSource *pixelgl.Window, referenceFrame pixel.Matrix) {
+func (e Entities) Input(actions *input.Actions) {
 	for _, ent := range e {
 		inp, ok := ent.(Inputtable)
 		if ok {
-			inp.Input(inputSource, referenceFrame)
-		}
-	}
-}
-
-func (e Entities) MidiInput(msgs chan midi.Message) {
-	msgSlice := make([]midi.Message, 0)
-
-	hasMessages := true
-	for hasMessages {
-		select {
-		case msg := <-msgs:
-			msgSlice = append(msgSlice, msg)
-		default:
-			hasMessages = false
-		}
-	}
-
-	for _, ent := range e {
-		inp, ok := ent.(Inputtable)
-		if ok {
-			inp.MidiInput(msgSlice)
+			inp.Input(actions)
 		}
 	}
 }
@@ -1,18 +1,18 @@
 package engine
 
 import (
-	"gitlab.com/gomidi/midi/writer"
+	"github.com/mateusz/carryall/engine/input"
 )
 
 type Outputtable interface {
-	MidiOutput(*writer.Writer)
+	MidiOutput(out *input.Output)
 }
 
-func (e Entities) MidiOutput(wr *writer.Writer) {
+func (e Entities) MidiOutput(out *input.Output) {
 	for _, ent := range e {
 		outp, ok := ent.(Outputtable)
 		if ok {
-			outp.MidiOutput(wr)
+			outp.MidiOutput(out)
 		}
 	}
 }
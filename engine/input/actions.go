@@ -0,0 +1,431 @@
+package input
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/faiface/pixel/pixelgl"
+	"gitlab.com/gomidi/midi/midimessage/channel"
+)
+
+// Actions turns MIDI, keyboard and gamepad input into named actions. Buttons can be held, pressed
+// and released, absolute controls give an axis in [0.0, 1.0] and relative ones add up a delta per frame.
+type Actions struct {
+	bindings []Binding
+	profile  *Profile
+	// Last value seen on each channel and cc, for the absolute jog wheels
+	lastCC map[[2]uint8]uint8
+	// Which half of a 14-bit control came first, while waiting for the other, by LSB
+	pairs map[[2]uint8]uint8
+	// Absolute controls before calibration, by control name
+	raw map[string]float64
+	// Recent ticks of each jog wheel binding, by index, on the controller and on the surface
+	jogs        map[int]*jogState
+	surfaceJogs map[int]*jogState
+	// Where smoothed axes are heading, and how slowly
+	targets   map[string]float64
+	smoothing map[string]float64
+
+	held     map[string]bool
+	pressed  map[string]bool
+	released map[string]bool
+	axes     map[string]float64
+	moved    map[string]bool
+	deltas   map[string]float64
+}
+
+// ControlEvent is a control moved by name rather than by a MIDI message, e.g. on an OSC surface.
+// Buttons are down from 0.5, faders and knobs go [0.0, 1.0] and jog wheels send ticks.
+type ControlEvent struct {
+	Control string
+	Value   float64
+	At      time.Time
+}
+
+// Smoothed axes snap to where they're heading once this close
+const AXIS_SETTLED = 0.0005
+
+func NewActions(bindingsPath string) *Actions {
+	return &Actions{
+		bindings:    LoadBindings(bindingsPath),
+		lastCC:      make(map[[2]uint8]uint8),
+		pairs:       make(map[[2]uint8]uint8),
+		raw:         make(map[string]float64),
+		jogs:        make(map[int]*jogState),
+		surfaceJogs: make(map[int]*jogState),
+		targets:     make(map[string]float64),
+		smoothing:   make(map[string]float64),
+		held:        make(map[string]bool),
+		pressed:     make(map[string]bool),
+		released:    make(map[string]bool),
+		axes:        make(map[string]float64),
+		moved:       make(map[string]bool),
+		deltas:      make(map[string]float64),
+	}
+}
+
+// SetProfile points the bindings at the controls of the controller that's plugged in.
+// Bindings to controls the profile doesn't have do nothing. Buttons held down on the
+// old controller are let go, they'll never see their note off.
+func (a *Actions) SetProfile(p *Profile) {
+	for action, down := range a.held {
+		if down {
+			a.setButton(action, false)
+		}
+	}
+
+	a.profile = p
+	a.pairs = make(map[[2]uint8]uint8)
+	for i := range a.bindings {
+		b := &a.bindings[i]
+		if b.Midi == nil || b.Midi.Control == "" {
+			continue
+		}
+		b.midi = nil
+		if p == nil {
+			continue
+		}
+		c, ok := p.Controls[b.Midi.Control]
+		if !ok {
+			fmt.Printf("Controller %s has no %s, %s is unbound\n", p.Name, b.Midi.Control, b.Action)
+			continue
+		}
+		b.midi = &c
+	}
+}
+
+// SetSurfaceProfile gives the controls an OSC surface sends by name, whatever controller is plugged in.
+func (a *Actions) SetSurfaceProfile(p *Profile) {
+	for i := range a.bindings {
+		b := &a.bindings[i]
+		b.surface = nil
+		if p == nil || b.Midi == nil || b.Midi.Control == "" {
+			continue
+		}
+		c, ok := p.Controls[b.Midi.Control]
+		if ok {
+			b.surface = &c
+		}
+	}
+}
+
+// Update starts a new frame, taking in everything that's waiting in the MIDI queue.
+func (a *Actions) Update(win *pixelgl.Window, msgs chan MidiEvent, dt float64) {
+	a.pressed = make(map[string]bool)
+	a.released = make(map[string]bool)
+	a.moved = make(map[string]bool)
+	a.deltas = make(map[string]float64)
+
+	hasMessages := true
+	for hasMessages {
+		select {
+		case ev := <-msgs:
+			a.Midi(ev)
+		default:
+			hasMessages = false
+		}
+	}
+
+	a.keyboard(win)
+	a.gamepad(win)
+
+	for action, target := range a.targets {
+		v := a.axes[action]
+		if math.Abs(target-v) < AXIS_SETTLED {
+			a.SetAxis(action, target)
+			delete(a.targets, action)
+			continue
+		}
+		a.SetAxis(action, v+(target-v)*(1.0-math.Exp(-dt/a.smoothing[action])))
+	}
+}
+
+// Midi applies a single message to whatever it's bound to.
+func (a *Actions) Midi(ev MidiEvent) {
+	msg := ev.Msg
+	// A 14-bit control is put together once per message, however many actions it's bound to
+	var hires map[[2]uint8]float64
+	for i, b := range a.bindings {
+		if b.midi == nil {
+			continue
+		}
+		c := b.midi
+
+		switch m := msg.(type) {
+		case channel.NoteOn:
+			if c.Note != nil && m.Channel() == c.Channel && m.Key() == *c.Note {
+				a.setButton(b.Action, m.Velocity() > 0)
+			}
+		case channel.NoteOff:
+			if c.Note != nil && m.Channel() == c.Channel && m.Key() == *c.Note {
+				a.setButton(b.Action, false)
+			}
+		case channel.ControlChange:
+			if c.CC == nil || m.Channel() != c.Channel {
+				continue
+			}
+			if c.LSB != nil && (m.Controller() == *c.CC || m.Controller() == *c.LSB) {
+				if hires == nil {
+					hires = make(map[[2]uint8]float64)
+				}
+				pair := [2]uint8{c.Channel, *c.LSB}
+				v, ok := hires[pair]
+				if !ok {
+					v = a.hires(c, m)
+					hires[pair] = v
+				}
+				a.axis(&b, b.Midi.Control, v)
+				continue
+			}
+			if m.Controller() != *c.CC {
+				continue
+			}
+			if !c.Relative {
+				a.axis(&b, b.Midi.Control, float64(m.Value())/127.0)
+				continue
+			}
+
+			jog := [2]uint8{c.Channel, *c.CC}
+			last, seen := a.lastCC[jog]
+			ticks := 0.0
+			if a.profile == nil {
+				ticks = jogTicks(JOG_TWOS_COMPLEMENT, m.Value(), last)
+			} else if a.profile.jog != JOG_ABSOLUTE || seen {
+				ticks = a.profile.Ticks(m.Value(), last)
+			}
+			if ticks != 0.0 {
+				if a.jogs[i] == nil {
+					a.jogs[i] = &jogState{}
+				}
+				a.AddDelta(b.Action, a.jogs[i].turn(b.Jog, ticks, ev.At))
+			}
+		}
+	}
+
+	// Absolute jog wheels count from wherever the last message left them, and 14-bit controls
+	// pair the half that just arrived with the last of the other
+	cc, ok := msg.(channel.ControlChange)
+	if ok {
+		a.lastCC[[2]uint8{cc.Channel(), cc.Controller()}] = cc.Value()
+	}
+}
+
+// Control applies a control moved by name to whatever it's bound to, treating it like the control
+// of the same name in the surface profile. Surfaces don't need calibrating.
+func (a *Actions) Control(ev ControlEvent) {
+	for i, b := range a.bindings {
+		if b.surface == nil || b.Midi.Control != ev.Control {
+			continue
+		}
+		c := b.surface
+
+		switch {
+		case c.Note != nil:
+			a.setButton(b.Action, ev.Value >= 0.5)
+		case c.Relative:
+			if ev.Value == 0.0 {
+				continue
+			}
+			if a.surfaceJogs[i] == nil {
+				a.surfaceJogs[i] = &jogState{}
+			}
+			a.AddDelta(b.Action, a.surfaceJogs[i].turn(b.Jog, ev.Value, ev.At))
+		default:
+			a.respond(&b, math.Max(0.0, math.Min(1.0, ev.Value)))
+		}
+	}
+}
+
+// hires puts a 14-bit control together from its MSB and LSB. Most controllers send the MSB first, and
+// as MIDI 1.0 has it a new MSB clears the LSB, so a move from 10:127 to 11:0 never passes through 11:127
+// on its way. Some send the LSB first though, and then it belongs with the MSB that follows.
+func (a *Actions) hires(c *MidiControl, m channel.ControlChange) float64 {
+	lsbKey := [2]uint8{c.Channel, *c.LSB}
+	msb := a.lastCC[[2]uint8{c.Channel, *c.CC}]
+	lsb := a.lastCC[lsbKey]
+
+	// Halves come in pairs, the one that arrives with nothing waiting, or the same half waiting, starts one
+	first, waiting := a.pairs[lsbKey]
+	completes := waiting && first != m.Controller()
+	if completes {
+		delete(a.pairs, lsbKey)
+	} else {
+		a.pairs[lsbKey] = m.Controller()
+	}
+
+	if m.Controller() == *c.CC {
+		msb = m.Value()
+		if !completes {
+			lsb = 0
+			a.lastCC[lsbKey] = 0
+		}
+	} else {
+		lsb = m.Value()
+	}
+	return float64(uint16(msb)<<7|uint16(lsb)) / 16383.0
+}
+
+func (a *Actions) keyboard(win *pixelgl.Window) {
+	for _, b := range a.bindings {
+		if b.Key == "" {
+			continue
+		}
+		if b.Value != 0.0 {
+			if win.Pressed(b.key) {
+				a.AddDelta(b.Action, b.Value)
+			}
+			continue
+		}
+		if win.JustPressed(b.key) {
+			a.setButton(b.Action, true)
+		}
+		if win.JustReleased(b.key) {
+			a.setButton(b.Action, false)
+		}
+	}
+}
+
+func (a *Actions) gamepad(win *pixelgl.Window) {
+	if !win.JoystickPresent(pixelgl.Joystick1) {
+		return
+	}
+	for _, b := range a.bindings {
+		if b.Gamepad == nil {
+			continue
+		}
+		g := b.Gamepad
+
+		if g.Button != nil {
+			button := pixelgl.GamepadButton(*g.Button)
+			if win.JoystickJustPressed(pixelgl.Joystick1, button) {
+				a.setButton(b.Action, true)
+			}
+			if win.JoystickJustReleased(pixelgl.Joystick1, button) {
+				a.setButton(b.Action, false)
+			}
+			continue
+		}
+
+		v := win.JoystickAxis(pixelgl.Joystick1, pixelgl.GamepadAxis(*g.Axis))
+		if b.Value != 0.0 {
+			a.AddDelta(b.Action, v*b.Value)
+			continue
+		}
+		// Sticks go [-1.0, 1.0]
+		stick := fmt.Sprintf("gamepad.axis.%d", *g.Axis)
+		last, seen := a.raw[stick]
+		if !seen || (v+1.0)/2.0 != last {
+			a.axis(&b, stick, (v+1.0)/2.0)
+		}
+	}
+}
+
+// axis takes an absolute control's raw value through its calibration and the binding's response.
+func (a *Actions) axis(b *Binding, control string, raw float64) {
+	// Raw MIDI bindings have no name to calibrate them by
+	if control != "" {
+		a.raw[control] = raw
+	}
+
+	v := raw
+	if a.profile != nil {
+		cal, ok := a.profile.Calibration[control]
+		if ok {
+			v = cal.Apply(raw)
+		}
+	}
+	a.respond(b, v)
+}
+
+// respond takes a [0.0, 1.0] value through the binding's response, smoothing it if asked to.
+func (a *Actions) respond(b *Binding, v float64) {
+	v = b.Response.Apply(v)
+
+	if b.Response != nil && b.Response.Smoothing > 0.0 {
+		a.targets[b.Action] = v
+		a.smoothing[b.Action] = b.Response.Smoothing
+		return
+	}
+	a.SetAxis(b.Action, v)
+}
+
+// Fader is an absolute control the bindings use, for the calibration screen.
+type Fader struct {
+	Control string
+	// Before calibration, if it's been moved at all
+	Raw     float64
+	Touched bool
+	Centred bool
+}
+
+// Faders lists the absolute controls of the controller that's bound to something.
+func (a *Actions) Faders() []Fader {
+	var faders []Fader
+	seen := make(map[string]bool)
+	for _, b := range a.bindings {
+		if b.midi == nil || b.Midi.Control == "" || b.midi.CC == nil || b.midi.Relative || seen[b.Midi.Control] {
+			continue
+		}
+		seen[b.Midi.Control] = true
+		raw, touched := a.raw[b.Midi.Control]
+		faders = append(faders, Fader{
+			Control: b.Midi.Control,
+			Raw:     raw,
+			Touched: touched,
+			Centred: b.Response != nil && b.Response.Centred,
+		})
+	}
+	return faders
+}
+
+func (a *Actions) setButton(action string, down bool) {
+	if down && !a.held[action] {
+		a.pressed[action] = true
+	}
+	if !down && a.held[action] {
+		a.released[action] = true
+	}
+	a.held[action] = down
+}
+
+// SetAxis moves an absolute control, [0.0, 1.0].
+func (a *Actions) SetAxis(action string, v float64) {
+	a.axes[action] = v
+	a.moved[action] = true
+}
+
+// AddDelta moves a relative control.
+func (a *Actions) AddDelta(action string, d float64) {
+	a.deltas[action] += d
+}
+
+// Held is true while the button is down.
+func (a *Actions) Held(action string) bool {
+	return a.held[action]
+}
+
+// Pressed is true on the frame the button went down.
+func (a *Actions) Pressed(action string) bool {
+	return a.pressed[action]
+}
+
+// Released is true on the frame the button came back up.
+func (a *Actions) Released(action string) bool {
+	return a.released[action]
+}
+
+// Axis is the last position of an absolute control.
+func (a *Actions) Axis(action string) float64 {
+	return a.axes[action]
+}
+
+// Moved is true on the frames an absolute control was touched.
+func (a *Actions) Moved(action string) bool {
+	return a.moved[action]
+}
+
+// Delta is how far a relative control went this frame.
+func (a *Actions) Delta(action string) float64 {
+	return a.deltas[action]
+}
@@ -0,0 +1,56 @@
+package input
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gitlab.com/gomidi/midi/midimessage/channel"
+)
+
+// 14-bit controls come out at full resolution whichever half the controller sends first.
+func TestHires(t *testing.T) {
+	dir, err := ioutil.TempDir("", "actions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bindings := filepath.Join(dir, "bindings.json")
+	writeFile(t, bindings, `{"bindings": [{"action": "throttle", "midi": {"control": "right.rate"}}]}`)
+
+	// MSB and LSB of each move
+	moves := [][2]uint8{{10, 127}, {11, 0}, {11, 5}, {10, 127}, {10, 126}}
+
+	for _, lsbFirst := range []bool{false, true} {
+		cc, lsb := uint8(9), uint8(41)
+		actions := NewActions(bindings)
+		actions.SetProfile(&Profile{Controls: map[string]MidiControl{
+			"right.rate": {Channel: 1, CC: &cc, LSB: &lsb},
+		}})
+		send := func(controller, v uint8) {
+			actions.Midi(MidiEvent{Msg: channel.Channel(1).ControlChange(controller, v), At: time.Now()})
+		}
+
+		for _, m := range moves {
+			if lsbFirst {
+				send(lsb, m[1])
+				send(cc, m[0])
+			} else {
+				send(cc, m[0])
+				// Nothing in between the old LSB and the new
+				if actions.Axis("throttle") != float64(uint16(m[0])<<7)/16383.0 {
+					t.Errorf("MSB first, %d:%d passed through %f", m[0], m[1], actions.Axis("throttle")*16383.0)
+				}
+				send(lsb, m[1])
+			}
+
+			want := float64(uint16(m[0])<<7|uint16(m[1])) / 16383.0
+			if actions.Axis("throttle") != want {
+				t.Errorf("LSB first %t, %d:%d came out as %f", lsbFirst, m[0], m[1], actions.Axis("throttle")*16383.0)
+			}
+		}
+	}
+}
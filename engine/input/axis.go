@@ -0,0 +1,91 @@
+package input
+
+import (
+	"fmt"
+	"math"
+	"os"
+)
+
+// AxisCalibration is where a particular fader or knob really sits, captured on the calibration
+// screen and kept in the controller's profile. All in raw [0.0, 1.0].
+type AxisCalibration struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+	// Where a centred control rests, e.g. a crossfader that clicks in slightly off the middle
+	Centre *float64 `json:"centre,omitempty"`
+}
+
+// AxisResponse shapes how an action feels, whichever control drives it.
+type AxisResponse struct {
+	// The action rests at 0.5 and works both ways from there, like the throttle
+	Centred bool `json:"centred,omitempty"`
+	// Fraction of the travel that does nothing, around the centre or at the bottom
+	DeadZone float64 `json:"deadZone,omitempty"`
+	// "expo" gives finer control near rest, "s" near both ends
+	Curve string `json:"curve,omitempty"`
+	// How much of the curve to mix in, [0.0, 1.0]
+	CurveAmount float64 `json:"curveAmount,omitempty"`
+	// Seconds to get most of the way to a new position, taking the edge off jumpy faders
+	Smoothing float64 `json:"smoothing,omitempty"`
+}
+
+func (r *AxisResponse) validate(action string) {
+	if r.Curve != "" && r.Curve != "expo" && r.Curve != "s" {
+		fmt.Printf("Error loading bindings: unknown curve %s for %s\n", r.Curve, action)
+		os.Exit(2)
+	}
+	if r.DeadZone < 0.0 || r.DeadZone >= 1.0 || r.CurveAmount < 0.0 || r.CurveAmount > 1.0 || r.Smoothing < 0.0 {
+		fmt.Printf("Error loading bindings: response for %s out of range\n", action)
+		os.Exit(2)
+	}
+}
+
+// Apply stretches a raw value over the calibrated travel.
+func (c AxisCalibration) Apply(raw float64) float64 {
+	if c.Max <= c.Min {
+		return raw
+	}
+
+	var v float64
+	if c.Centre == nil || *c.Centre <= c.Min || *c.Centre >= c.Max {
+		v = (raw - c.Min) / (c.Max - c.Min)
+	} else if raw < *c.Centre {
+		v = 0.5 * (raw - c.Min) / (*c.Centre - c.Min)
+	} else {
+		v = 0.5 + 0.5*(raw-*c.Centre)/(c.Max-*c.Centre)
+	}
+	return math.Max(0.0, math.Min(1.0, v))
+}
+
+// Apply puts a [0.0, 1.0] value through the dead zone and the curve. Smoothing happens over time in Actions.
+func (r *AxisResponse) Apply(v float64) float64 {
+	if r == nil {
+		return v
+	}
+	if !r.Centred {
+		return r.shape(v)
+	}
+
+	// Both halves work outwards from the middle
+	m := r.shape(math.Abs(v*2.0 - 1.0))
+	if v < 0.5 {
+		return 0.5 - m/2.0
+	}
+	return 0.5 + m/2.0
+}
+
+// shape works on how far from rest, [0.0, 1.0].
+func (r *AxisResponse) shape(m float64) float64 {
+	if m <= r.DeadZone {
+		return 0.0
+	}
+	m = (m - r.DeadZone) / (1.0 - r.DeadZone)
+
+	switch r.Curve {
+	case "expo":
+		m = (1.0-r.CurveAmount)*m + r.CurveAmount*m*m*m
+	case "s":
+		m = (1.0-r.CurveAmount)*m + r.CurveAmount*m*m*(3.0-2.0*m)
+	}
+	return m
+}
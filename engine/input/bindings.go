@@ -0,0 +1,118 @@
+package input
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/faiface/pixel/pixelgl"
+)
+
+// Binding ties one physical control to a named action. Exactly one of Midi, Key and Gamepad is set.
+type Binding struct {
+	Action  string          `json:"action"`
+	Midi    *MidiControl    `json:"midi,omitempty"`
+	Key     string          `json:"key,omitempty"`
+	Gamepad *GamepadControl `json:"gamepad,omitempty"`
+	// Turns a key or a gamepad axis into a relative control, adding Value (times the axis) every frame it's held
+	Value float64 `json:"value,omitempty"`
+	// Shapes absolute controls
+	Response *AxisResponse `json:"response,omitempty"`
+	// Shapes jog wheels
+	Jog *JogResponse `json:"jog,omitempty"`
+
+	key pixelgl.Button
+	// Midi, or the profile's control it names
+	midi *MidiControl
+	// The control it names on an OSC surface
+	surface *MidiControl
+}
+
+// MidiControl is a note (a button) or a CC (a fader, knob or jog wheel) on a MIDI channel.
+// Bindings usually name a Control from the controller's profile instead.
+type MidiControl struct {
+	Control string `json:"control,omitempty"`
+	Channel uint8  `json:"channel"`
+	Note    *uint8 `json:"note,omitempty"`
+	CC      *uint8 `json:"cc,omitempty"`
+	// Second CC carrying the low 7 bits of a high resolution fader or knob, usually CC+32
+	LSB *uint8 `json:"lsb,omitempty"`
+	// Jog wheels, counted the way the profile says
+	Relative bool `json:"relative,omitempty"`
+}
+
+// GamepadControl is a button or an axis of the first joystick.
+type GamepadControl struct {
+	Button *int `json:"button,omitempty"`
+	Axis   *int `json:"axis,omitempty"`
+}
+
+type bindingsConfig struct {
+	Bindings []Binding `json:"bindings"`
+}
+
+// Looked up by the names pixelgl gives them, e.g. "Left", "PageUp" or "MouseButtonLeft".
+var keysByName map[string]pixelgl.Button
+
+func init() {
+	keysByName = make(map[string]pixelgl.Button)
+	for b := pixelgl.Button(0); b <= pixelgl.KeyLast; b++ {
+		name := b.String()
+		if name != "Invalid" {
+			keysByName[name] = b
+		}
+	}
+}
+
+// LoadBindings reads the bindings file, exiting if it doesn't make sense.
+func LoadBindings(path string) []Binding {
+	var cfg bindingsConfig
+
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Printf("Error finding bindings: %s\n", err)
+		os.Exit(2)
+	}
+	defer f.Close()
+
+	err = json.NewDecoder(f).Decode(&cfg)
+	if err != nil {
+		fmt.Printf("Error loading bindings: %s\n", err)
+		os.Exit(2)
+	}
+
+	for i := range cfg.Bindings {
+		b := &cfg.Bindings[i]
+		if b.Key != "" {
+			key, ok := keysByName[b.Key]
+			if !ok {
+				fmt.Printf("Error loading bindings: unknown key %s for %s\n", b.Key, b.Action)
+				os.Exit(2)
+			}
+			b.key = key
+		}
+		if b.Midi != nil && b.Midi.Control == "" && (b.Midi.Note == nil) == (b.Midi.CC == nil) {
+			fmt.Printf("Error loading bindings: midi control for %s needs a control, a note or a cc\n", b.Action)
+			os.Exit(2)
+		}
+		if b.Midi != nil && b.Midi.LSB != nil && (b.Midi.CC == nil || b.Midi.Relative) {
+			fmt.Printf("Error loading bindings: only faders and knobs for %s can have an lsb\n", b.Action)
+			os.Exit(2)
+		}
+		if b.Midi != nil && b.Midi.Control == "" {
+			b.midi = b.Midi
+		}
+		if b.Response != nil {
+			b.Response.validate(b.Action)
+		}
+		if b.Jog != nil {
+			b.Jog.validate(b.Action)
+		}
+		if b.Gamepad != nil && (b.Gamepad.Button == nil) == (b.Gamepad.Axis == nil) {
+			fmt.Printf("Error loading bindings: gamepad control for %s needs either a button or an axis\n", b.Action)
+			os.Exit(2)
+		}
+	}
+
+	return cfg.Bindings
+}
@@ -0,0 +1,90 @@
+package input
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"gitlab.com/gomidi/midi"
+)
+
+// Velocity is averaged over this long unless the binding says otherwise
+const JOG_DEFAULT_WINDOW = 0.1
+
+// MidiEvent is a message stamped with when it arrived, rather than when the frame got round to it.
+type MidiEvent struct {
+	Msg midi.Message
+	At  time.Time
+}
+
+// JogResponse makes a fast spin go further per tick than a slow nudge. How fast is measured
+// in ticks a second of wall time, so the frame rate doesn't come into it.
+type JogResponse struct {
+	// Every tick is worth this much, e.g. less on the top of the wheel for fine adjustments
+	Scale float64 `json:"scale,omitempty"`
+	// Ticks a second the wheel can turn before accelerating
+	Threshold float64 `json:"threshold,omitempty"`
+	// Extra gain for each tick a second above the threshold
+	Acceleration float64 `json:"acceleration,omitempty"`
+	// Most a tick can be multiplied by, unlimited if not set
+	MaxGain float64 `json:"maxGain,omitempty"`
+	// Seconds to average the speed over
+	Window float64 `json:"window,omitempty"`
+}
+
+func (r *JogResponse) validate(action string) {
+	if r.Scale < 0.0 || r.Threshold < 0.0 || r.Acceleration < 0.0 || r.MaxGain < 0.0 || r.Window < 0.0 {
+		fmt.Printf("Error loading bindings: jog response for %s out of range\n", action)
+		os.Exit(2)
+	}
+}
+
+type jogTick struct {
+	at    time.Time
+	ticks float64
+}
+
+// jogState remembers a wheel's recent ticks to tell how fast it's going.
+type jogState struct {
+	recent []jogTick
+}
+
+// Velocity in ticks a second, either way.
+func (s *jogState) velocity(window float64) float64 {
+	sum := 0.0
+	for _, t := range s.recent {
+		sum += t.ticks
+	}
+	return sum / window
+}
+
+// turn takes in some ticks and says how far they go.
+func (s *jogState) turn(r *JogResponse, ticks float64, at time.Time) float64 {
+	window := JOG_DEFAULT_WINDOW
+	if r != nil && r.Window > 0.0 {
+		window = r.Window
+	}
+
+	since := at.Add(-time.Duration(window * float64(time.Second)))
+	kept := s.recent[:0]
+	for _, t := range s.recent {
+		if t.at.After(since) {
+			kept = append(kept, t)
+		}
+	}
+	s.recent = append(kept, jogTick{at: at, ticks: ticks})
+
+	if r == nil {
+		return ticks
+	}
+
+	gain := 1.0 + r.Acceleration*math.Max(0.0, math.Abs(s.velocity(window))-r.Threshold)
+	if r.MaxGain > 0.0 {
+		gain = math.Min(gain, r.MaxGain)
+	}
+	if r.Scale > 0.0 {
+		gain *= r.Scale
+	}
+	return ticks * gain
+}
@@ -0,0 +1,208 @@
+package input
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// OscMessage is an Open Sound Control message. Args are int32, float32, string or bool,
+// whatever else arrives is read as the nearest of those.
+type OscMessage struct {
+	Address string
+	Args    []interface{}
+}
+
+// Float is the first argument as a number, e.g. a fader's position. Without one it's a press, so 1.0.
+func (m OscMessage) Float() (float64, bool) {
+	if len(m.Args) == 0 {
+		return 1.0, true
+	}
+	switch v := m.Args[0].(type) {
+	case float32:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case bool:
+		if v {
+			return 1.0, true
+		}
+		return 0.0, true
+	}
+	return 0.0, false
+}
+
+// Bytes encodes the message for sending.
+func (m OscMessage) Bytes() []byte {
+	var b bytes.Buffer
+	oscString(&b, m.Address)
+
+	tags := ","
+	var args bytes.Buffer
+	for _, arg := range m.Args {
+		switch v := arg.(type) {
+		case int32:
+			tags += "i"
+			binary.Write(&args, binary.BigEndian, v)
+		case float32:
+			tags += "f"
+			binary.Write(&args, binary.BigEndian, math.Float32bits(v))
+		case string:
+			tags += "s"
+			oscString(&args, v)
+		case bool:
+			if v {
+				tags += "T"
+			} else {
+				tags += "F"
+			}
+		}
+	}
+	oscString(&b, tags)
+	b.Write(args.Bytes())
+	return b.Bytes()
+}
+
+// OscBundle packs messages into one packet, to be acted on straight away.
+func OscBundle(msgs []OscMessage) []byte {
+	var b bytes.Buffer
+	oscString(&b, "#bundle")
+	// Time tag 1 means immediately
+	binary.Write(&b, binary.BigEndian, uint64(1))
+	for _, m := range msgs {
+		data := m.Bytes()
+		binary.Write(&b, binary.BigEndian, int32(len(data)))
+		b.Write(data)
+	}
+	return b.Bytes()
+}
+
+// ParseOsc reads a packet, a single message or a bundle of them.
+func ParseOsc(packet []byte) ([]OscMessage, error) {
+	if bytes.HasPrefix(packet, []byte("#bundle\x00")) {
+		if len(packet) < 16 {
+			return nil, fmt.Errorf("bundle too short")
+		}
+		var msgs []OscMessage
+		rest := packet[16:]
+		for len(rest) > 0 {
+			if len(rest) < 4 {
+				return nil, fmt.Errorf("bundle element without a size")
+			}
+			size := int(binary.BigEndian.Uint32(rest))
+			rest = rest[4:]
+			if size < 0 || size > len(rest) {
+				return nil, fmt.Errorf("bundle element longer than the bundle")
+			}
+			inner, err := ParseOsc(rest[:size])
+			if err != nil {
+				return nil, err
+			}
+			msgs = append(msgs, inner...)
+			rest = rest[size:]
+		}
+		return msgs, nil
+	}
+
+	m, err := parseOscMessage(packet)
+	if err != nil {
+		return nil, err
+	}
+	return []OscMessage{m}, nil
+}
+
+func parseOscMessage(b []byte) (OscMessage, error) {
+	var m OscMessage
+	address, b, err := readOscString(b)
+	if err != nil {
+		return m, err
+	}
+	if len(address) == 0 || address[0] != '/' {
+		return m, fmt.Errorf("bad address %q", address)
+	}
+	m.Address = address
+
+	// Very old senders leave the type tags out, there's nothing to go on then
+	if len(b) == 0 {
+		return m, nil
+	}
+	tags, b, err := readOscString(b)
+	if err != nil {
+		return m, err
+	}
+	if len(tags) == 0 || tags[0] != ',' {
+		return m, fmt.Errorf("bad type tags %q for %s", tags, address)
+	}
+
+	for _, tag := range tags[1:] {
+		var size int
+		switch tag {
+		case 'i', 'f':
+			size = 4
+		case 'h', 'd', 't':
+			size = 8
+		}
+		if len(b) < size {
+			return m, fmt.Errorf("%s is missing arguments", address)
+		}
+
+		switch tag {
+		case 'i':
+			m.Args = append(m.Args, int32(binary.BigEndian.Uint32(b)))
+		case 'f':
+			m.Args = append(m.Args, math.Float32frombits(binary.BigEndian.Uint32(b)))
+		case 'h', 't':
+			m.Args = append(m.Args, int32(binary.BigEndian.Uint64(b)))
+		case 'd':
+			m.Args = append(m.Args, float32(math.Float64frombits(binary.BigEndian.Uint64(b))))
+		case 's', 'S':
+			var s string
+			s, b, err = readOscString(b)
+			if err != nil {
+				return m, err
+			}
+			m.Args = append(m.Args, s)
+		case 'b':
+			// Blobs are skipped over
+			if len(b) < 4 {
+				return m, fmt.Errorf("%s is missing arguments", address)
+			}
+			size = 4 + oscPadded(int(binary.BigEndian.Uint32(b)))
+			if size < 4 || len(b) < size {
+				return m, fmt.Errorf("%s is missing arguments", address)
+			}
+		case 'T':
+			m.Args = append(m.Args, true)
+		case 'F':
+			m.Args = append(m.Args, false)
+		case 'N', 'I':
+		default:
+			return m, fmt.Errorf("unknown type tag %c for %s", tag, address)
+		}
+		b = b[size:]
+	}
+	return m, nil
+}
+
+// Strings end with a zero and are padded to four bytes
+func oscString(b *bytes.Buffer, s string) {
+	b.WriteString(s)
+	b.Write(make([]byte, oscPadded(len(s)+1)-len(s)))
+}
+
+func readOscString(b []byte) (string, []byte, error) {
+	end := bytes.IndexByte(b, 0)
+	if end < 0 {
+		return "", nil, fmt.Errorf("unterminated string")
+	}
+	size := oscPadded(end + 1)
+	if size > len(b) {
+		size = len(b)
+	}
+	return string(b[:end]), b[size:], nil
+}
+
+func oscPadded(n int) int {
+	return (n + 3) &^ 3
+}
@@ -0,0 +1,207 @@
+package input
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OSC addresses are one of these followed by the name of the control, LED, meter or display,
+// e.g. /control/left.play or /meter/left.meter
+const (
+	OSC_CONTROL = "/control/"
+	OSC_LED     = "/led/"
+	OSC_METER   = "/meter/"
+	OSC_DISPLAY = "/display/"
+)
+
+// Feedback is split into bundles of about this many bytes, so they fit in a packet
+const OSC_MAX_PACKET = 1024
+
+type oscConfig struct {
+	// Where to listen for a surface, e.g. ":8000". No OSC at all if empty
+	Listen string `json:"listen"`
+	// Where feedback goes, e.g. "192.168.1.20:9000". Back to whichever surface was heard last if empty
+	Send string `json:"send"`
+	// Port the surface listens on, when replying to it. 9000 if not set
+	ReplyPort int `json:"replyPort"`
+}
+
+// OscSurface is a tablet control surface talking OSC over UDP. Its controls go by the same
+// names as the ones in the controller profiles, so the bindings work for it unchanged, and
+// it gets sent the same LEDs and meters as the controller, plus the displays.
+type OscSurface struct {
+	conn   *net.UDPConn
+	config oscConfig
+	queue  chan ControlEvent
+
+	mu sync.Mutex
+	to *net.UDPAddr
+	// Set when feedback starts going somewhere new, until the output catches up
+	changed bool
+}
+
+// NewOscSurface starts listening for a surface. Nil if OSC isn't configured.
+func NewOscSurface(configPath string) *OscSurface {
+	s := &OscSurface{
+		queue: make(chan ControlEvent, 128),
+	}
+
+	f, err := os.Open(configPath)
+	if err != nil {
+		fmt.Printf("Error finding osc config: %s\n", err)
+		os.Exit(2)
+	}
+	defer f.Close()
+
+	err = json.NewDecoder(f).Decode(&s.config)
+	if err != nil {
+		fmt.Printf("Error loading osc config: %s\n", err)
+		os.Exit(2)
+	}
+	if s.config.Listen == "" {
+		return nil
+	}
+	if s.config.ReplyPort == 0 {
+		s.config.ReplyPort = 9000
+	}
+	if s.config.Send != "" {
+		s.to, err = net.ResolveUDPAddr("udp", s.config.Send)
+		if err != nil {
+			fmt.Printf("Error loading osc config: %s\n", err)
+			os.Exit(2)
+		}
+		s.changed = true
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", s.config.Listen)
+	if err != nil {
+		fmt.Printf("Error loading osc config: %s\n", err)
+		os.Exit(2)
+	}
+	s.conn, err = net.ListenUDP("udp", addr)
+	if err != nil {
+		fmt.Printf("No OSC surface, can't listen: %s\n", err)
+		return nil
+	}
+	fmt.Printf("Listening for OSC on %s\n", s.conn.LocalAddr())
+	go s.listen()
+
+	return s
+}
+
+func (s *OscSurface) listen() {
+	packet := make([]byte, 65536)
+	for {
+		n, from, err := s.conn.ReadFromUDP(packet)
+		if err != nil {
+			// Closed
+			return
+		}
+		at := time.Now()
+
+		msgs, err := ParseOsc(packet[:n])
+		if err != nil {
+			fmt.Printf("Bad OSC packet from %s: %s\n", from, err)
+			continue
+		}
+		s.heard(from)
+
+		for _, m := range msgs {
+			if !strings.HasPrefix(m.Address, OSC_CONTROL) {
+				continue
+			}
+			v, ok := m.Float()
+			if !ok {
+				continue
+			}
+			// Dropped rather than stall if the game isn't keeping up
+			select {
+			case s.queue <- ControlEvent{Control: strings.TrimPrefix(m.Address, OSC_CONTROL), Value: v, At: at}:
+			default:
+			}
+		}
+	}
+}
+
+// heard points the feedback at a surface that's just been in touch, unless it goes somewhere fixed.
+func (s *OscSurface) heard(from *net.UDPAddr) {
+	if s.config.Send != "" {
+		return
+	}
+	to := &net.UDPAddr{IP: from.IP, Port: s.config.ReplyPort, Zone: from.Zone}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.to != nil && s.to.String() == to.String() {
+		return
+	}
+	s.to = to
+	s.changed = true
+	fmt.Printf("OSC surface at %s\n", to)
+}
+
+// hasChanged is true once after the feedback started going somewhere new.
+func (s *OscSurface) hasChanged() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := s.changed
+	s.changed = false
+	return changed
+}
+
+// Poll applies everything the surface sent since the last frame.
+func (s *OscSurface) Poll(actions *Actions) {
+	if s == nil {
+		return
+	}
+	for {
+		select {
+		case ev := <-s.queue:
+			actions.Control(ev)
+		default:
+			return
+		}
+	}
+}
+
+// Send goes to the surface, if there's one to send to.
+func (s *OscSurface) Send(msgs []OscMessage) {
+	if s == nil || len(msgs) == 0 {
+		return
+	}
+	s.mu.Lock()
+	to := s.to
+	s.mu.Unlock()
+	if to == nil {
+		return
+	}
+
+	var bundle []OscMessage
+	size := 0
+	for _, m := range msgs {
+		n := len(m.Bytes()) + 4
+		if len(bundle) > 0 && size+n > OSC_MAX_PACKET {
+			s.conn.WriteToUDP(OscBundle(bundle), to)
+			bundle = nil
+			size = 0
+		}
+		bundle = append(bundle, m)
+		size += n
+	}
+	s.conn.WriteToUDP(OscBundle(bundle), to)
+}
+
+func (s *OscSurface) Close() {
+	if s == nil {
+		return
+	}
+	s.conn.Close()
+}
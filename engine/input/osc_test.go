@@ -0,0 +1,137 @@
+package input
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOscRoundTrip(t *testing.T) {
+	msgs := []OscMessage{
+		{Address: "/control/left.play", Args: []interface{}{float32(1.0)}},
+		{Address: "/display/radio", Args: []interface{}{"LW 153.00kHz AM", int32(-3), true, false}},
+		{Address: "/control/left.jogRim"},
+	}
+
+	for _, m := range msgs {
+		got, err := ParseOsc(m.Bytes())
+		if err != nil {
+			t.Fatalf("%s: %s", m.Address, err)
+		}
+		if !reflect.DeepEqual(got, []OscMessage{m}) {
+			t.Errorf("%s came back as %+v", m.Address, got)
+		}
+	}
+
+	got, err := ParseOsc(OscBundle(msgs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, msgs) {
+		t.Errorf("bundle came back as %+v", got)
+	}
+
+	// Cut short, the last float is missing
+	b := msgs[0].Bytes()
+	_, err = ParseOsc(b[:len(b)-4])
+	if err == nil {
+		t.Error("truncated message parsed")
+	}
+}
+
+// A surface on loopback drives the actions and gets feedback sent back to it.
+func TestOscSurface(t *testing.T) {
+	dir, err := ioutil.TempDir("", "osc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	client, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	config := filepath.Join(dir, "osc.json")
+	writeFile(t, config, fmt.Sprintf(`{"listen": "127.0.0.1:0", "replyPort": %d}`, client.LocalAddr().(*net.UDPAddr).Port))
+	bindings := filepath.Join(dir, "bindings.json")
+	writeFile(t, bindings, `{"bindings": [
+		{"action": "spinup", "midi": {"control": "left.play"}},
+		{"action": "throttle", "midi": {"control": "right.volume"}},
+		{"action": "rotate", "midi": {"control": "left.jogRim"}}
+	]}`)
+
+	note, cc, jog := uint8(7), uint8(0), uint8(9)
+	actions := NewActions(bindings)
+	actions.SetSurfaceProfile(&Profile{Controls: map[string]MidiControl{
+		"left.play":    {Note: &note},
+		"right.volume": {Channel: 1, CC: &cc},
+		"left.jogRim":  {CC: &jog, Relative: true},
+	}})
+
+	s := NewOscSurface(config)
+	if s == nil {
+		t.Fatal("no surface")
+	}
+	defer s.Close()
+
+	packet := OscBundle([]OscMessage{
+		{Address: OSC_CONTROL + "left.play", Args: []interface{}{float32(1.0)}},
+		{Address: OSC_CONTROL + "right.volume", Args: []interface{}{float32(0.25)}},
+		{Address: OSC_CONTROL + "left.jogRim", Args: []interface{}{int32(-3)}},
+		{Address: OSC_CONTROL + "nothing.bound"},
+	})
+	_, err = client.WriteToUDP(packet, s.conn.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !actions.Moved("throttle") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		s.Poll(actions)
+	}
+	if !actions.Held("spinup") || !actions.Pressed("spinup") {
+		t.Error("spinup not pressed")
+	}
+	if actions.Axis("throttle") != 0.25 {
+		t.Errorf("throttle at %f", actions.Axis("throttle"))
+	}
+	if actions.Delta("rotate") != -3.0 {
+		t.Errorf("rotated by %f", actions.Delta("rotate"))
+	}
+
+	// Feedback goes back to the surface that was heard from
+	if !s.hasChanged() {
+		t.Fatal("surface not heard from")
+	}
+	sent := []OscMessage{{Address: OSC_LED + "left.play", Args: []interface{}{float32(1.0)}}}
+	s.Send(sent)
+
+	client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 1024)
+	n, _, err := client.ReadFromUDP(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseOsc(buf[:n])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, sent) {
+		t.Errorf("surface got %+v", got)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	err := ioutil.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
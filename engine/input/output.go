@@ -0,0 +1,288 @@
+package input
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"gitlab.com/gomidi/midi"
+	"gitlab.com/gomidi/midi/writer"
+)
+
+// What an LED is asked to do for the frame.
+const (
+	LED_OFF = iota
+	LED_ON
+	// On and off, a number of times a second
+	LED_BLINK
+	// Fading up and down, a number of times a second. Just blinks on LEDs that can't dim
+	LED_PULSE
+)
+
+// At most this many messages go out a frame, the rest wait for the next one. MIDI manages
+// about a thousand a second and some controllers choke well before that.
+const OUTPUT_MAX_MESSAGES = 12
+
+// After a raw message, e.g. from a light show, the LEDs are left alone for this long
+const OUTPUT_RAW_HOLD = 500 * time.Millisecond
+
+// Pulsing LEDs step through this many brightnesses, so they don't send every frame
+const OUTPUT_PULSE_STEPS = 8
+
+type ledState struct {
+	mode int
+	rate float64
+}
+
+// Output talks back to the controller, lighting LEDs and meters by the names the profile gives them,
+// and to the OSC surface if there is one. Entities say what they want shown every frame between Begin
+// and Flush, anything they don't mention goes dark. Only what changed since the last Flush is sent.
+// Without a controller everything sent to it is dropped.
+type Output struct {
+	wr      *writer.Writer
+	profile *Profile
+	surface *OscSurface
+	start   time.Time
+
+	leds     map[string]ledState
+	levels   map[string]float64
+	displays map[string]string
+	// Whether each LED came out lit in the last Flush, for the virtual controller
+	lit map[string]bool
+
+	// What the controller was last sent, by LED or meter. Missing if it's anyone's guess
+	sent map[string]uint8
+	// LEDs and meters in the profile, in the order they get their turn
+	names []string
+	next  int
+	rawAt time.Time
+	// What the surface was last sent, by address
+	oscSent map[string]interface{}
+}
+
+func NewOutput(wr *writer.Writer, profile *Profile) *Output {
+	o := &Output{
+		wr:       wr,
+		start:    time.Now(),
+		leds:     make(map[string]ledState),
+		levels:   make(map[string]float64),
+		displays: make(map[string]string),
+		lit:      make(map[string]bool),
+		oscSent:  make(map[string]interface{}),
+	}
+	o.SetProfile(profile)
+	return o
+}
+
+// SetProfile switches to another controller. It gets sent everything on the next Flush.
+func (o *Output) SetProfile(p *Profile) {
+	o.profile = p
+	o.sent = make(map[string]uint8)
+	o.names = nil
+	o.next = 0
+	if p == nil {
+		return
+	}
+	for led := range p.Leds {
+		o.names = append(o.names, led)
+	}
+	for meter := range p.Meters {
+		o.names = append(o.names, meter)
+	}
+	sort.Strings(o.names)
+}
+
+// SetSurface sends to an OSC surface as well as the controller.
+func (o *Output) SetSurface(s *OscSurface) {
+	o.surface = s
+	o.oscSent = make(map[string]interface{})
+}
+
+// Begin starts a new frame with everything off.
+func (o *Output) Begin() {
+	o.leds = make(map[string]ledState)
+	o.levels = make(map[string]float64)
+	o.displays = make(map[string]string)
+}
+
+// Light turns an LED on or off. LEDs the controller doesn't have are ignored.
+func (o *Output) Light(led string, on bool) {
+	if on {
+		o.leds[led] = ledState{mode: LED_ON}
+	} else {
+		delete(o.leds, led)
+	}
+}
+
+// Blink flashes an LED, rate times a second.
+func (o *Output) Blink(led string, rate float64) {
+	o.leds[led] = ledState{mode: LED_BLINK, rate: rate}
+}
+
+// Pulse fades an LED up and down, rate times a second.
+func (o *Output) Pulse(led string, rate float64) {
+	o.leds[led] = ledState{mode: LED_PULSE, rate: rate}
+}
+
+// Level sets a meter, [0.0, 1.0].
+func (o *Output) Level(meter string, v float64) {
+	o.levels[meter] = math.Max(0.0, math.Min(1.0, v))
+}
+
+// Display shows a line of text, e.g. the radio frequency. Only surfaces have displays.
+func (o *Output) Display(display string, text string) {
+	o.displays[display] = text
+}
+
+// Lit is whether the LED came out lit in the last Flush, controller or not.
+func (o *Output) Lit(led string) bool {
+	return o.lit[led]
+}
+
+// Write sends a raw message, e.g. a light show recorded for this controller. The LEDs are
+// left to it for a while, then put back the way the game wants them.
+func (o *Output) Write(msg midi.Message) {
+	if o.wr == nil {
+		return
+	}
+	o.rawAt = time.Now()
+	o.sent = make(map[string]uint8)
+	o.wr.Write(msg)
+}
+
+// Flush sends whatever changed since the last frame.
+func (o *Output) Flush(now time.Time) {
+	o.lit = make(map[string]bool)
+	for led, s := range o.leds {
+		o.lit[led] = o.brightness(s, now) >= 0.5
+	}
+	o.flushSurface(now)
+
+	if o.wr == nil || o.profile == nil || now.Sub(o.rawAt) < OUTPUT_RAW_HOLD {
+		return
+	}
+
+	// Round robin, so something changing every frame can't hold the rest up
+	sent := 0
+	for i := 0; i < len(o.names) && sent < OUTPUT_MAX_MESSAGES; i++ {
+		name := o.names[(o.next+i)%len(o.names)]
+		v := o.value(name, now)
+		last, ok := o.sent[name]
+		if ok && last == v {
+			continue
+		}
+		o.send(name, v)
+		o.sent[name] = v
+		sent++
+		if sent == OUTPUT_MAX_MESSAGES {
+			o.next = (o.next + i + 1) % len(o.names)
+		}
+	}
+}
+
+// flushSurface sends the surface whatever changed. It's all in a bundle or two, so no need to hold back.
+func (o *Output) flushSurface(now time.Time) {
+	if o.surface == nil {
+		return
+	}
+	if o.surface.hasChanged() {
+		o.oscSent = make(map[string]interface{})
+	}
+
+	// Whatever was sent before and isn't wanted now goes back to nothing
+	values := make(map[string]interface{})
+	for address, v := range o.oscSent {
+		switch v.(type) {
+		case string:
+			values[address] = ""
+		default:
+			values[address] = float32(0.0)
+		}
+	}
+	for led, s := range o.leds {
+		values[OSC_LED+led] = float32(o.brightness(s, now))
+	}
+	for meter, v := range o.levels {
+		values[OSC_METER+meter] = float32(v)
+	}
+	for display, text := range o.displays {
+		values[OSC_DISPLAY+display] = text
+	}
+
+	addresses := make([]string, 0, len(values))
+	for address := range values {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	var msgs []OscMessage
+	for _, address := range addresses {
+		v := values[address]
+		last, ok := o.oscSent[address]
+		if ok && last == v {
+			continue
+		}
+		msgs = append(msgs, OscMessage{Address: address, Args: []interface{}{v}})
+		o.oscSent[address] = v
+	}
+	o.surface.Send(msgs)
+}
+
+// brightness of an LED at the moment, [0.0, 1.0].
+func (o *Output) brightness(s ledState, now time.Time) float64 {
+	phase := math.Mod(now.Sub(o.start).Seconds()*s.rate, 1.0)
+	switch s.mode {
+	case LED_ON:
+		return 1.0
+	case LED_BLINK:
+		if phase < 0.5 {
+			return 1.0
+		}
+		return 0.0
+	case LED_PULSE:
+		b := (1.0 - math.Cos(2.0*math.Pi*phase)) / 2.0
+		return math.Round(b*OUTPUT_PULSE_STEPS) / OUTPUT_PULSE_STEPS
+	}
+	return 0.0
+}
+
+// value is the velocity or controller value that shows what's wanted of an LED or a meter.
+func (o *Output) value(name string, now time.Time) uint8 {
+	if m, ok := o.profile.Meters[name]; ok {
+		return uint8(math.Round(o.levels[name] * float64(m.Max)))
+	}
+
+	l := o.profile.Leds[name]
+	s, ok := o.leds[name]
+	if !ok {
+		return 0
+	}
+	b := o.brightness(s, now)
+	if !l.Dimmable {
+		if b >= 0.5 {
+			return l.On
+		}
+		return 0
+	}
+	return uint8(math.Ceil(b * float64(l.On)))
+}
+
+func (o *Output) send(name string, v uint8) {
+	if m, ok := o.profile.Meters[name]; ok {
+		o.wr.SetChannel(m.Channel)
+		if m.CC != nil {
+			writer.ControlChange(o.wr, *m.CC, v)
+		} else {
+			writer.NoteOn(o.wr, *m.Note, v)
+		}
+		return
+	}
+
+	l := o.profile.Leds[name]
+	o.wr.SetChannel(l.Channel)
+	if v > 0 {
+		writer.NoteOn(o.wr, l.Note, v)
+	} else {
+		writer.NoteOff(o.wr, l.Note)
+	}
+}
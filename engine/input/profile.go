@@ -0,0 +1,231 @@
+package input
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// How a controller's jog wheels report a turn.
+const (
+	// 1 is a tick clockwise, 127 a tick anticlockwise
+	JOG_TWOS_COMPLEMENT = iota
+	// Bit 6 set means anticlockwise, the rest is the number of ticks
+	JOG_SIGN_MAGNITUDE
+	// The wheel's position, wrapping around at 128
+	JOG_ABSOLUTE
+)
+
+var jogEncodingNames = map[string]int{
+	"twosComplement": JOG_TWOS_COMPLEMENT,
+	"signMagnitude":  JOG_SIGN_MAGNITUDE,
+	"absolute":       JOG_ABSOLUTE,
+}
+
+// Profile describes one controller model: what its controls are called, how its jog wheels
+// count and which notes light its LEDs. Bindings refer to the controls by name, so the same
+// bindings work across controllers.
+type Profile struct {
+	Name string `json:"name"`
+	// Picked when the MIDI port name contains any of these
+	Ports []string `json:"ports"`
+	// Used when no other profile matches the port
+	Default  bool                   `json:"default,omitempty"`
+	Jog      string                 `json:"jog"`
+	Controls map[string]MidiControl `json:"controls"`
+	Leds     map[string]Led         `json:"leds"`
+	Meters   map[string]Meter       `json:"meters,omitempty"`
+	// By control, written by the calibration screen
+	Calibration map[string]AxisCalibration `json:"calibration,omitempty"`
+
+	jog  int
+	path string
+}
+
+// Led is lit with a note on, and turned off with a note off.
+type Led struct {
+	Channel uint8 `json:"channel"`
+	Note    uint8 `json:"note"`
+	// Velocity to light with, some controllers pick the colour by it. 127 if not set
+	On uint8 `json:"on,omitempty"`
+	// Brightness follows the velocity, so the LED can pulse smoothly
+	Dimmable bool `json:"dimmable,omitempty"`
+}
+
+// Meter is a row of LEDs showing a level, e.g. a VU meter, set by a cc or a note's velocity.
+type Meter struct {
+	Channel uint8  `json:"channel"`
+	CC      *uint8 `json:"cc,omitempty"`
+	Note    *uint8 `json:"note,omitempty"`
+	// Value for a full meter. 127 if not set
+	Max uint8 `json:"max,omitempty"`
+}
+
+// LoadProfiles reads every profile in the directory.
+func LoadProfiles(dir string) []*Profile {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		fmt.Printf("Error finding controller profiles: %s\n", err)
+		os.Exit(2)
+	}
+	sort.Strings(paths)
+
+	profiles := make([]*Profile, 0, len(paths))
+	for _, path := range paths {
+		profiles = append(profiles, loadProfile(path))
+	}
+	return profiles
+}
+
+func loadProfile(path string) *Profile {
+	var p Profile
+
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Printf("Error finding controller profile: %s\n", err)
+		os.Exit(2)
+	}
+	defer f.Close()
+
+	err = json.NewDecoder(f).Decode(&p)
+	if err != nil {
+		fmt.Printf("Error loading controller profile %s: %s\n", path, err)
+		os.Exit(2)
+	}
+
+	jog, ok := jogEncodingNames[p.Jog]
+	if !ok {
+		fmt.Printf("Error loading controller profile %s: unknown jog encoding %s\n", path, p.Jog)
+		os.Exit(2)
+	}
+	p.jog = jog
+	p.path = path
+	if p.Calibration == nil {
+		p.Calibration = make(map[string]AxisCalibration)
+	}
+
+	for name, c := range p.Controls {
+		if (c.Note == nil) == (c.CC == nil) {
+			fmt.Printf("Error loading controller profile %s: control %s needs either a note or a cc\n", path, name)
+			os.Exit(2)
+		}
+		if c.LSB != nil && (c.CC == nil || c.Relative) {
+			fmt.Printf("Error loading controller profile %s: only faders and knobs like %s can have an lsb\n", path, name)
+			os.Exit(2)
+		}
+		if c.Control != "" {
+			fmt.Printf("Error loading controller profile %s: control %s can't refer to another control\n", path, name)
+			os.Exit(2)
+		}
+	}
+	for name, l := range p.Leds {
+		if l.On == 0 {
+			l.On = 0x7F
+			p.Leds[name] = l
+		}
+	}
+	for name, m := range p.Meters {
+		if (m.Note == nil) == (m.CC == nil) {
+			fmt.Printf("Error loading controller profile %s: meter %s needs either a note or a cc\n", path, name)
+			os.Exit(2)
+		}
+		if _, ok := p.Leds[name]; ok {
+			fmt.Printf("Error loading controller profile %s: %s is both an LED and a meter\n", path, name)
+			os.Exit(2)
+		}
+		if m.Max == 0 {
+			m.Max = 0x7F
+			p.Meters[name] = m
+		}
+	}
+
+	return &p
+}
+
+// Save writes the profile back where it came from, e.g. after calibrating.
+func (p *Profile) Save() error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(p.path, append(data, '\n'), 0644)
+}
+
+// ProfileFor picks the profile for a MIDI port, falling back to the default one. Nil if there's neither.
+func ProfileFor(profiles []*Profile, port string) *Profile {
+	var fallback *Profile
+	for _, p := range profiles {
+		if p.Matches(port) {
+			return p
+		}
+		if p.Default && fallback == nil {
+			fallback = p
+		}
+	}
+	return fallback
+}
+
+// Matches is true if the profile was written for the controller on the MIDI port, rather than
+// just being the default.
+func (p *Profile) Matches(port string) bool {
+	for _, match := range p.Ports {
+		if strings.Contains(strings.ToLower(port), strings.ToLower(match)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Ticks turns a jog wheel message into how far it went. Absolute wheels need the previous position.
+func (p *Profile) Ticks(value, last uint8) float64 {
+	return jogTicks(p.jog, value, last)
+}
+
+// JogValue is what a jog wheel sends for a number of ticks, the other way round from Ticks.
+// Absolute wheels need the previous position.
+func (p *Profile) JogValue(ticks int, last uint8) uint8 {
+	if ticks > 63 {
+		ticks = 63
+	} else if ticks < -63 {
+		ticks = -63
+	}
+
+	switch p.jog {
+	case JOG_SIGN_MAGNITUDE:
+		if ticks < 0 {
+			return 0x40 | uint8(-ticks)
+		}
+		return uint8(ticks)
+	case JOG_ABSOLUTE:
+		return uint8((int(last) + ticks) & 0x7F)
+	default:
+		return uint8(ticks & 0x7F)
+	}
+}
+
+func jogTicks(encoding int, value, last uint8) float64 {
+	switch encoding {
+	case JOG_SIGN_MAGNITUDE:
+		if value&0x40 != 0 {
+			return -float64(value & 0x3F)
+		}
+		return float64(value & 0x3F)
+	case JOG_ABSOLUTE:
+		d := int(value) - int(last)
+		if d > 64 {
+			d -= 128
+		} else if d < -64 {
+			d += 128
+		}
+		return float64(d)
+	default:
+		if value >= 64 {
+			return float64(int(value) - 128)
+		}
+		return float64(value)
+	}
+}
@@ -0,0 +1,87 @@
+package sid
+
+import (
+	"math"
+	"sync"
+)
+
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+// Coefficients from the RBJ audio EQ cookbook, Butterworth Q.
+func newBiquad(highPass bool, freq, sampleRate float64) biquad {
+	w := 2.0 * math.Pi * freq / sampleRate
+	alpha := math.Sin(w) / (2.0 * math.Sqrt2 / 2.0)
+	cos := math.Cos(w)
+	a0 := 1.0 + alpha
+
+	b := biquad{
+		a1: -2.0 * cos / a0,
+		a2: (1.0 - alpha) / a0,
+	}
+	if highPass {
+		b.b0 = (1.0 + cos) / 2.0 / a0
+		b.b1 = -(1.0 + cos) / a0
+	} else {
+		b.b0 = (1.0 - cos) / 2.0 / a0
+		b.b1 = (1.0 - cos) / a0
+	}
+	b.b2 = b.b0
+	return b
+}
+
+func (b *biquad) process(x float64) float64 {
+	y := b.b0*x + b.b1*b.x1 + b.b2*b.x2 - b.a1*b.y1 - b.a2*b.y2
+	b.x2, b.x1 = b.x1, x
+	b.y2, b.y1 = b.y1, y
+	return y
+}
+
+// BandPass keeps the source between low and high Hz, like the audio stage of a radio.
+type BandPass struct {
+	source     SignalSource
+	low        float64
+	high       float64
+	hp         biquad
+	lp         biquad
+	sampleRate float64
+	mu         sync.Mutex
+}
+
+func NewBandPass(s SignalSource, low, high float64) *BandPass {
+	return &BandPass{
+		source: s,
+		low:    low,
+		high:   high,
+	}
+}
+
+func (s *BandPass) Reset() {
+	s.mu.Lock()
+	s.sampleRate = 0.0
+	s.mu.Unlock()
+	s.source.Reset()
+}
+
+func (s *BandPass) Gen(sampleRate float64) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sampleRate != sampleRate {
+		s.hp = newBiquad(true, s.low, sampleRate)
+		s.lp = newBiquad(false, s.high, sampleRate)
+		s.sampleRate = sampleRate
+	}
+
+	return s.lp.process(s.hp.process(s.source.Gen(sampleRate)))
+}
+
+func (s *BandPass) Lock() {
+	s.source.Lock()
+}
+
+func (s *BandPass) Unlock() {
+	s.source.Unlock()
+}
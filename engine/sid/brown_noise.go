@@ -0,0 +1,51 @@
+package sid
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// BrownNoise integrates white noise, with a slight leak to keep it from wandering off.
+type BrownNoise struct {
+	value float64
+	seed  int64
+	rng   *rand.Rand
+	mu    sync.Mutex
+}
+
+func NewBrownNoise(seed int64) *BrownNoise {
+	return &BrownNoise{
+		seed: seed,
+		rng:  rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (s *BrownNoise) Reset() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.rng.Seed(s.seed)
+	s.value = 0.0
+}
+
+func (s *BrownNoise) Gen(sampleRate float64) float64 {
+	s.Lock()
+	defer s.Unlock()
+
+	s.value = s.value*0.998 + (s.rng.Float64()*2.0-1.0)*0.04
+	if s.value > 1.0 {
+		s.value = 1.0
+	}
+	if s.value < -1.0 {
+		s.value = -1.0
+	}
+	return s.value
+}
+
+func (s *BrownNoise) Lock() {
+	s.mu.Lock()
+}
+
+func (s *BrownNoise) Unlock() {
+	s.mu.Unlock()
+}
@@ -0,0 +1,55 @@
+package sid
+
+import (
+	"sync"
+)
+
+// Burst plays the source for a while and then ends, e.g. a crackle of static standing in for a reply.
+type Burst struct {
+	source   SignalSource
+	duration float64 // seconds
+	left     float64
+	mu       sync.Mutex
+}
+
+func NewBurst(s SignalSource, duration float64) *Burst {
+	return &Burst{
+		source:   s,
+		duration: duration,
+		left:     duration,
+	}
+}
+
+func (s *Burst) HasEnded() bool {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.left <= 0.0
+}
+
+func (s *Burst) Reset() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.left = s.duration
+	s.source.Reset()
+}
+
+func (s *Burst) Gen(sampleRate float64) float64 {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.left <= 0.0 {
+		return 0.0
+	}
+	s.left -= 1.0 / sampleRate
+	return s.source.Gen(sampleRate)
+}
+
+func (s *Burst) Lock() {
+	s.mu.Lock()
+}
+
+func (s *Burst) Unlock() {
+	s.mu.Unlock()
+}
@@ -6,8 +6,10 @@ const (
 )
 
 type Channel struct {
-	src         SignalSource
-	volume      float64
+	src    SignalSource
+	volume float64
+	// -1.0 = left, 1.0 = right
+	pan         float64
 	paused      bool
 	fadeSamples int
 	// 0 = faded completely, fadeSamples = unfaded
@@ -0,0 +1,59 @@
+package sid
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// CrackleNoise fires sparse impulses of random height that die away within a few samples.
+type CrackleNoise struct {
+	density float64 // impulses per second
+	decay   float64
+	level   float64
+	seed    int64
+	rng     *rand.Rand
+	mu      sync.Mutex
+}
+
+func NewCrackleNoise(density float64, seed int64) *CrackleNoise {
+	return &CrackleNoise{
+		density: density,
+		decay:   0.6,
+		seed:    seed,
+		rng:     rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (s *CrackleNoise) SetDensity(d float64) {
+	s.Lock()
+	s.density = d
+	s.Unlock()
+}
+
+func (s *CrackleNoise) Reset() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.rng.Seed(s.seed)
+	s.level = 0.0
+}
+
+func (s *CrackleNoise) Gen(sampleRate float64) float64 {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.rng.Float64() < s.density/sampleRate {
+		s.level = s.rng.Float64()*2.0 - 1.0
+	}
+	smp := s.level
+	s.level *= -s.decay
+	return smp
+}
+
+func (s *CrackleNoise) Lock() {
+	s.mu.Lock()
+}
+
+func (s *CrackleNoise) Unlock() {
+	s.mu.Unlock()
+}
@@ -0,0 +1,92 @@
+package sid
+
+import (
+	"math"
+	"sync"
+)
+
+const SID_HILBERT_TAPS = 65
+
+// FrequencyShift moves every frequency of the source by the same number of Hz, like a mistuned
+// single-sideband receiver. It uses a windowed FIR Hilbert transformer to get the quadrature signal.
+type FrequencyShift struct {
+	source SignalSource
+	shift  float64
+	phase  float64
+	taps   []float64
+	delay  []float64
+	pos    int
+	mu     sync.Mutex
+}
+
+func NewFrequencyShift(s SignalSource) *FrequencyShift {
+	f := &FrequencyShift{
+		source: s,
+		taps:   make([]float64, SID_HILBERT_TAPS),
+		delay:  make([]float64, SID_HILBERT_TAPS),
+	}
+
+	// Taps are stored oldest sample first, hence counted backwards from the centre
+	centre := SID_HILBERT_TAPS / 2
+	for i := range f.taps {
+		n := centre - i
+		if n%2 == 0 {
+			continue
+		}
+		// Hamming window
+		window := 0.54 - 0.46*math.Cos(2.0*math.Pi*float64(i)/float64(SID_HILBERT_TAPS-1))
+		f.taps[i] = 2.0 / (math.Pi * float64(n)) * window
+	}
+
+	return f
+}
+
+// SetShift sets the shift in Hz, negative values shift down.
+func (s *FrequencyShift) SetShift(hz float64) {
+	s.mu.Lock()
+	s.shift = hz
+	s.mu.Unlock()
+}
+
+func (s *FrequencyShift) Reset() {
+	s.mu.Lock()
+	for i := range s.delay {
+		s.delay[i] = 0.0
+	}
+	s.phase = 0.0
+	s.mu.Unlock()
+	s.source.Reset()
+}
+
+func (s *FrequencyShift) Gen(sampleRate float64) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.delay[s.pos] = s.source.Gen(sampleRate)
+	s.pos = (s.pos + 1) % len(s.delay)
+
+	// Oldest sample sits at s.pos
+	quadrature := 0.0
+	for i, t := range s.taps {
+		if t != 0.0 {
+			quadrature += t * s.delay[(s.pos+i)%len(s.delay)]
+		}
+	}
+	// In-phase is delayed to line up with the centre of the filter
+	inPhase := s.delay[(s.pos+len(s.taps)/2)%len(s.delay)]
+
+	smp := inPhase*math.Cos(2.0*math.Pi*s.phase) - quadrature*math.Sin(2.0*math.Pi*s.phase)
+	_, s.phase = math.Modf(s.phase + s.shift/sampleRate)
+	if s.phase < 0.0 {
+		s.phase += 1.0
+	}
+	return smp
+}
+
+func (s *FrequencyShift) Lock() {
+	s.source.Lock()
+}
+
+func (s *FrequencyShift) Unlock() {
+	s.source.Unlock()
+}
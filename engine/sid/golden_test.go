@@ -0,0 +1,208 @@
+package sid
+
+import (
+	"encoding/binary"
+	"flag"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	testSampleRate    = 44100.0
+	testRenderSamples = 4096
+	// Largest difference from golden allowed for any one sample
+	testTolerance = 1e-5
+)
+
+var update = flag.Bool("update", false, "rewrite the golden buffers instead of comparing")
+
+type fixture struct {
+	name   string
+	render func(out []float32)
+}
+
+// Renders every SignalSource and compares it against the checked-in golden buffers. Noise is seeded,
+// so the output only changes when the DSP does. After an intentional change run with -update.
+func TestGolden(t *testing.T) {
+	for _, f := range fixtures() {
+		f := f
+		t.Run(f.name, func(t *testing.T) {
+			out := make([]float32, testRenderSamples)
+			f.render(out)
+
+			path := filepath.Join("testdata", "golden", f.name+".f32")
+			if *update {
+				err := writeGolden(path, out)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			golden, err := readGolden(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			worst, at := compare(out, golden)
+			if worst > testTolerance {
+				t.Errorf("sample %d off by %g", at, worst)
+			}
+		})
+	}
+}
+
+// Assets are shared with the game
+func asset(name string) string {
+	return filepath.Join("..", "..", "assets", name)
+}
+
+func fixtures() []fixture {
+	return []fixture{
+		{"silence", source(func() SignalSource { return &Silence{} })},
+		{"sine", source(func() SignalSource { return NewSine(440.0, 1) })},
+		{"sine_aliquots", source(func() SignalSource { return NewSine(440.0, 4) })},
+		{"vibrato", source(func() SignalSource { return NewVibrato(20.0, 1.02, 1.05) })},
+		{"random_noise", source(func() SignalSource { return NewRandomNoise(1) })},
+		{"pink_noise_5", source(func() SignalSource { return NewPinkNoise(5, 1) })},
+		{"pink_noise_8", source(func() SignalSource { return NewPinkNoise(8, 1) })},
+		{"pink_noise_13", source(func() SignalSource { return NewPinkNoise(13, 1) })},
+		{"brown_noise", source(func() SignalSource { return NewBrownNoise(1) })},
+		{"crackle_noise", source(func() SignalSource { return NewCrackleNoise(200.0, 1) })},
+		{"volume_adjust", source(func() SignalSource { return NewVolumeAdjust(NewSine(440.0, 1), 0.1) })},
+		{"mix", source(func() SignalSource {
+			return NewMix([]SignalSource{NewSine(440.0, 1), NewSine(660.0, 2)})
+		})},
+		{"weighted_mix", source(func() SignalSource {
+			m := NewWeightedMix()
+			m.SetInputs([]SignalSource{NewSine(440.0, 1), NewSine(660.0, 2)}, []float64{0.8, 0.3})
+			return m
+		})},
+		{"low_pass", source(func() SignalSource { return NewLowPass(NewRandomNoise(1), 512, 3000.0) })},
+		{"frequency_shift", source(func() SignalSource {
+			f := NewFrequencyShift(NewMix([]SignalSource{NewSine(440.0, 1), NewSine(1200.0, 1)}))
+			f.SetShift(-250.0)
+			return f
+		})},
+		{"morse", source(func() SignalSource { return NewMorse(NewSine(800.0, 1), "E T", 240.0) })},
+		{"band_pass", source(func() SignalSource { return NewBandPass(NewRandomNoise(1), 300.0, 3000.0) })},
+		{"mp3", source(func() SignalSource { return NewMp3(asset("ground_alert.mp3"), false) })},
+		{"mp3_loop", source(func() SignalSource { return NewMp3(asset("ground_alert.mp3"), true) })},
+		{"sequence", source(func() SignalSource {
+			return NewSequence([]*Mp3{NewMp3(asset("ground_alert.mp3"), false)}, []float64{0.05})
+		})},
+		{"burst", source(func() SignalSource { return NewBurst(NewCrackleNoise(2000.0, 1), 0.05) })},
+		{"granular", source(func() SignalSource {
+			g := NewGranular([][]float32{LoadMp3Samples(asset("ground_alert.mp3"))}, 1)
+			g.SetDensity(2000.0)
+			g.SetGrainLength(0.01)
+			g.SetPitchSpread(5.0)
+			return g
+		})},
+		{"stems", source(func() SignalSource {
+			s := NewStems([]SignalSource{NewSine(220.0, 1), NewSine(330.0, 1)}, 2400.0, 1)
+			s.SetLayer(0, 1.0)
+			s.SetLayer(1, 0.5)
+			return s
+		})},
+		{"synth", source(func() SignalSource {
+			s := NewSynth()
+			s.Schedule([]SynthEvent{
+				{At: 0.0, Channel: 0, Key: 69, Velocity: 127, On: true},
+				{At: 0.02, Channel: 0, Key: 76, Velocity: 64, On: true},
+				{At: 0.05, Channel: 0, Key: 69},
+			})
+			return s
+		})},
+		{"sid_mix", renderSid},
+		{"sid_mix_stereo", renderSidStereo},
+	}
+}
+
+func source(mk func() SignalSource) func([]float32) {
+	return func(out []float32) {
+		src := mk()
+		for i := range out {
+			out[i] = float32(src.Gen(testSampleRate))
+		}
+	}
+}
+
+func renderSid(out []float32) {
+	s := New(map[string]*Channel{
+		"a": NewChannel(0.5),
+		"b": NewChannel(0.25),
+		"c": NewChannel(1.0),
+	})
+	s.SetSource("a", NewSine(440.0, 1))
+	s.SetSource("b", NewVibrato(80.0, 1.02, 1.05))
+	s.SetSource("c", NewSine(3000.0, 2))
+	s.Pause("c")
+	s.Mix(out, testSampleRate)
+}
+
+// Left channel in the first half of the buffer, right in the second.
+func renderSidStereo(out []float32) {
+	s := New(map[string]*Channel{
+		"a": NewChannel(0.5),
+		"b": NewChannel(0.5),
+	})
+	s.SetSource("a", NewSine(440.0, 1))
+	s.SetSource("b", NewSine(660.0, 1))
+	s.SetPan("a", -0.5)
+	s.SetPan("b", 1.0)
+	s.MixStereo(out[:len(out)/2], out[len(out)/2:], testSampleRate)
+}
+
+func compare(out, golden []float32) (float64, int) {
+	if len(out) != len(golden) {
+		return math.Inf(1), 0
+	}
+
+	worst := 0.0
+	at := 0
+	for i := range out {
+		d := math.Abs(float64(out[i] - golden[i]))
+		if math.IsNaN(d) {
+			return math.Inf(1), i
+		}
+		if d > worst {
+			worst = d
+			at = i
+		}
+	}
+	return worst, at
+}
+
+func writeGolden(path string, buf []float32) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return binary.Write(f, binary.LittleEndian, buf)
+}
+
+func readGolden(path string) ([]float32, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]float32, fi.Size()/4)
+	err = binary.Read(f, binary.LittleEndian, buf)
+	return buf, err
+}
@@ -0,0 +1,117 @@
+package sid
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+)
+
+const SID_GRANULAR_MAX_GRAINS = 32
+
+type grain struct {
+	buf    []float32
+	pos    float64
+	rate   float64
+	length float64
+	age    float64
+}
+
+// Granular sprays short, windowed grains picked at random from the source samples.
+type Granular struct {
+	buffers     [][]float32
+	grains      []*grain
+	rng         *rand.Rand
+	density     float64 // grains per second
+	grainLength float64 // seconds
+	pitchSpread float64 // semitones either way
+	mu          sync.Mutex
+}
+
+func NewGranular(buffers [][]float32, seed int64) *Granular {
+	return &Granular{
+		buffers:     buffers,
+		rng:         rand.New(rand.NewSource(seed)),
+		density:     10.0,
+		grainLength: 0.1,
+	}
+}
+
+func (s *Granular) SetDensity(d float64) {
+	s.Lock()
+	s.density = d
+	s.Unlock()
+}
+
+func (s *Granular) SetGrainLength(l float64) {
+	s.Lock()
+	s.grainLength = l
+	s.Unlock()
+}
+
+func (s *Granular) SetPitchSpread(semitones float64) {
+	s.Lock()
+	s.pitchSpread = semitones
+	s.Unlock()
+}
+
+func (s *Granular) Reset() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.grains = s.grains[:0]
+}
+
+func (s *Granular) Gen(sampleRate float64) float64 {
+	s.Lock()
+	defer s.Unlock()
+
+	if len(s.buffers) > 0 && len(s.grains) < SID_GRANULAR_MAX_GRAINS && s.rng.Float64() < s.density/sampleRate {
+		s.spawn(sampleRate)
+	}
+
+	smp := 0.0
+	alive := s.grains[:0]
+	for _, g := range s.grains {
+		idx := int(g.pos)
+		if idx >= len(g.buf) || g.age >= g.length {
+			continue
+		}
+
+		// Hann window
+		window := 0.5 - 0.5*math.Cos(2.0*math.Pi*g.age/g.length)
+		smp += float64(g.buf[idx]) * window
+
+		g.pos += g.rate
+		g.age++
+		alive = append(alive, g)
+	}
+	s.grains = alive
+
+	return smp
+}
+
+func (s *Granular) spawn(sampleRate float64) {
+	buf := s.buffers[s.rng.Intn(len(s.buffers))]
+	length := s.grainLength * sampleRate * (0.5 + s.rng.Float64())
+	rate := math.Pow(2.0, (s.rng.Float64()*2.0-1.0)*s.pitchSpread/12.0)
+
+	span := len(buf) - int(length*rate) - 1
+	if span <= 0 {
+		return
+	}
+
+	s.grains = append(s.grains, &grain{
+		buf:    buf,
+		pos:    float64(s.rng.Intn(span)),
+		rate:   rate,
+		length: length,
+	})
+}
+
+func (s *Granular) Lock() {
+	s.mu.Lock()
+}
+
+func (s *Granular) Unlock() {
+	s.mu.Unlock()
+}
@@ -1,45 +1,74 @@
 package sid
 
 import (
-	"fmt"
-	"os"
+	"sync"
 
 	"github.com/mjibson/go-dsp/fft"
 )
 
+// LowPass filters the source in blocks of bufsize samples, dropping all FFT bins above the cutoff.
 type LowPass struct {
 	source  SignalSource
+	cutoff  float64
 	buf     []float64
+	out     []float64
 	current int
+	mu      sync.Mutex
 }
 
-func NewLowPass(s SignalSource, bufsize int) *LowPass {
+func NewLowPass(s SignalSource, bufsize int, cutoff float64) *LowPass {
 	f := &LowPass{
 		source:  s,
+		cutoff:  cutoff,
 		buf:     make([]float64, bufsize),
+		out:     make([]float64, bufsize),
 		current: bufsize,
 	}
 
 	return f
 }
 
+func (s *LowPass) SetCutoff(hz float64) {
+	s.mu.Lock()
+	s.cutoff = hz
+	s.mu.Unlock()
+}
+
 func (s *LowPass) Reset() {
+	s.mu.Lock()
 	s.current = len(s.buf)
+	s.mu.Unlock()
 	s.source.Reset()
 }
 
 func (s *LowPass) Gen(sampleRate float64) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	if s.current >= len(s.buf) {
 		for i := 0; i < len(s.buf); i++ {
 			s.buf[i] = s.source.Gen(sampleRate)
 		}
 
+		n := len(s.buf)
 		f := fft.FFTReal(s.buf)
-		fmt.Printf("%+v\n", f)
-		os.Exit(1)
+		for k := range f {
+			// Bins past the middle mirror the negative frequencies
+			bin := k
+			if bin > n/2 {
+				bin = n - k
+			}
+			if float64(bin)*sampleRate/float64(n) > s.cutoff {
+				f[k] = 0
+			}
+		}
+		for i, c := range fft.IFFT(f) {
+			s.out[i] = real(c)
+		}
+		s.current = 0
 	}
 
-	smp := s.buf[s.current]
+	smp := s.out[s.current]
 	s.current++
 	return smp
 }
@@ -0,0 +1,101 @@
+package sid
+
+import (
+	"math"
+	"strings"
+	"sync"
+)
+
+// Seconds for the key to open or close, so the keying doesn't click
+const SID_MORSE_RAMP = 0.005
+
+var morseCodes = map[rune]string{
+	'A': ".-", 'B': "-...", 'C': "-.-.", 'D': "-..", 'E': ".", 'F': "..-.", 'G': "--.", 'H': "....",
+	'I': "..", 'J': ".---", 'K': "-.-", 'L': ".-..", 'M': "--", 'N': "-.", 'O': "---", 'P': ".--.",
+	'Q': "--.-", 'R': ".-.", 'S': "...", 'T': "-", 'U': "..-", 'V': "...-", 'W': ".--", 'X': "-..-",
+	'Y': "-.--", 'Z': "--..",
+	'0': "-----", '1': ".----", '2': "..---", '3': "...--", '4': "....-",
+	'5': ".....", '6': "-....", '7': "--...", '8': "---..", '9': "----.",
+}
+
+// Morse keys the source on and off to spell out the text, over and over.
+type Morse struct {
+	source  SignalSource
+	pattern []bool // Key state for each dot length
+	dot     float64
+	pos     float64
+	level   float64
+	mu      sync.Mutex
+}
+
+// NewMorse spells text at wpm words per minute (PARIS timing), with a word gap before repeating.
+// Characters without a Morse code are skipped.
+func NewMorse(source SignalSource, text string, wpm float64) *Morse {
+	m := &Morse{
+		source: source,
+		dot:    1.2 / wpm,
+	}
+
+	for _, word := range strings.Fields(strings.ToUpper(text)) {
+		for _, c := range word {
+			code, ok := morseCodes[c]
+			if !ok {
+				continue
+			}
+			for _, el := range code {
+				if el == '-' {
+					m.pattern = append(m.pattern, true, true, true)
+				} else {
+					m.pattern = append(m.pattern, true)
+				}
+				m.pattern = append(m.pattern, false)
+			}
+			// Letter gap is three dots, one came with the last element
+			m.pattern = append(m.pattern, false, false)
+		}
+		// Word gap is seven dots
+		m.pattern = append(m.pattern, false, false, false, false)
+	}
+
+	return m
+}
+
+func (s *Morse) Reset() {
+	s.mu.Lock()
+	s.pos = 0.0
+	s.level = 0.0
+	s.mu.Unlock()
+	s.source.Reset()
+}
+
+func (s *Morse) Gen(sampleRate float64) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	smp := s.source.Gen(sampleRate)
+	if len(s.pattern) == 0 {
+		return 0.0
+	}
+
+	target := 0.0
+	if s.pattern[int(s.pos/s.dot)%len(s.pattern)] {
+		target = 1.0
+	}
+	ramp := 1.0 / (SID_MORSE_RAMP * sampleRate)
+	if s.level < target {
+		s.level = math.Min(s.level+ramp, target)
+	} else {
+		s.level = math.Max(s.level-ramp, target)
+	}
+
+	s.pos = math.Mod(s.pos+1.0/sampleRate, s.dot*float64(len(s.pattern)))
+	return smp * s.level
+}
+
+func (s *Morse) Lock() {
+	s.source.Lock()
+}
+
+func (s *Morse) Unlock() {
+	s.source.Unlock()
+}
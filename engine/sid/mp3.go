@@ -3,6 +3,7 @@ package sid
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"sync"
 
@@ -45,6 +46,48 @@ func NewMp3(path string, loop bool) *Mp3 {
 	return &m
 }
 
+// LoadMp3Samples decodes the whole file into memory as mono samples.
+func LoadMp3Samples(path string) []float32 {
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Printf("Error finding mp3: %s\n", err)
+		os.Exit(2)
+	}
+	defer f.Close()
+
+	decoder, err := mp3.NewDecoder(f)
+	if err != nil {
+		fmt.Printf("Error loading mp3: %s\n", err)
+		os.Exit(2)
+	}
+
+	raw, err := ioutil.ReadAll(decoder)
+	if err != nil {
+		fmt.Printf("Error decoding mp3: %s\n", err)
+		os.Exit(2)
+	}
+
+	smps := make([]float32, len(raw)/4)
+	for i := range smps {
+		smps[i] = float32(decodeStereoSample(raw[i*4 : i*4+4]))
+	}
+
+	return smps
+}
+
+// Quoting from mp3.Decoder:
+//
+//	The stream is always formatted as 16bit (little endian) 2 channels
+//	even if the source is single channel MP3.
+//	Thus, a sample always consists of 4 bytes.
+func decodeStereoSample(b []byte) float64 {
+	ch1 := int16(uint16(b[0]) | uint16(b[1])<<8)
+	ch2 := int16(uint16(b[2]) | uint16(b[3])<<8)
+
+	// Average out the channels - we are running mono here! Rescale to -1.0..1.0
+	return (float64(ch1) + float64(ch2)) / 2.0 / 32768.0
+}
+
 func (s *Mp3) HasEnded() bool {
 	s.Lock()
 	defer s.Unlock()
@@ -76,15 +119,7 @@ func (s *Mp3) innerGen(sampleRate float64) float64 {
 	n, err := s.decoder.Read(s.buf)
 
 	if n == 4 && err == nil {
-		// Quoting from mp3.Decoder:
-		// 	The stream is always formatted as 16bit (little endian) 2 channels
-		// 	even if the source is single channel MP3.
-		// 	Thus, a sample always consists of 4 bytes.
-		ch1 := int16(s.buf[0]) + 256*int16(s.buf[1])
-		ch2 := int16(s.buf[0]) + 256*int16(s.buf[1])
-
-		// Average out the channels - we are running mono here!
-		avg := float64(ch1+ch2) / 2.0
+		avg := decodeStereoSample(s.buf)
 
 		fade := 1.0
 		if !s.loop {
@@ -98,8 +133,7 @@ func (s *Mp3) innerGen(sampleRate float64) float64 {
 
 		s.currentSample++
 
-		// Rescale from 0..65535 to -1.0..1.0
-		return ((avg/65536.0)*2.0 - 1.0) * fade
+		return avg * fade
 	} else if err == io.EOF {
 		if s.loop {
 			s.decoder.Seek(0, io.SeekStart)
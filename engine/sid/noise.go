@@ -4,15 +4,25 @@ import (
 	"math/rand"
 )
 
+// RandomNoise is white noise. Each source owns its generator, so a given seed always renders the same.
 type RandomNoise struct {
+	seed int64
+	rng  *rand.Rand
 }
 
-func (s *RandomNoise) Reset() {
+func NewRandomNoise(seed int64) *RandomNoise {
+	return &RandomNoise{
+		seed: seed,
+		rng:  rand.New(rand.NewSource(seed)),
+	}
+}
 
+func (s *RandomNoise) Reset() {
+	s.rng.Seed(s.seed)
 }
 
 func (s *RandomNoise) Gen(sampleRate float64) float64 {
-	return (rand.Float64() * 2.0) - 1.0
+	return (s.rng.Float64() * 2.0) - 1.0
 }
 
 func (s *RandomNoise) Lock() {
@@ -1,47 +1,64 @@
 package sid
 
 import (
+	"math/bits"
 	"math/rand"
 )
 
+// PinkNoise uses the Voss-McCartney algorithm: each sample refreshes a single row, picked by
+// the trailing zeros of a counter, so row n changes every 2^(n+1) samples.
 type PinkNoise struct {
 	granularity int
 	values      []float64
-	key         int
+	sum         float64
+	key         uint
+	seed        int64
+	rng         *rand.Rand
 }
 
-func NewPinkNoise(granularity int) *PinkNoise {
-	return &PinkNoise{
+func NewPinkNoise(granularity int, seed int64) *PinkNoise {
+	s := &PinkNoise{
 		granularity: granularity,
 		values:      make([]float64, granularity),
-		key:         0,
+		seed:        seed,
+		rng:         rand.New(rand.NewSource(seed)),
 	}
+	s.fill()
+	return s
 }
 
-func (s *PinkNoise) Reset() {
+func (s *PinkNoise) fill() {
+	s.key = 0
+	s.sum = 0.0
+	for v := range s.values {
+		s.values[v] = s.rng.Float64() * s.subSampleVol()
+		s.sum += s.values[v]
+	}
+}
 
+// Add 1 to account for the extra always-on sample
+func (s *PinkNoise) subSampleVol() float64 {
+	return 1.0 / float64(s.granularity+1)
 }
 
-func (s *PinkNoise) Gen(sampleRate float64) float64 {
-	// Add 1 to account for the extra always-on sample
-	subSampleVol := 1.0 / float64(s.granularity+1)
+func (s *PinkNoise) Reset() {
+	s.rng.Seed(s.seed)
+	s.fill()
+}
 
-	lastKey := s.key
+func (s *PinkNoise) Gen(sampleRate float64) float64 {
 	s.key++
-	if s.key >= 1<<s.granularity {
+	row := bits.TrailingZeros(s.key)
+	if row < s.granularity {
+		s.sum -= s.values[row]
+		s.values[row] = s.rng.Float64() * s.subSampleVol()
+		s.sum += s.values[row]
+	} else {
 		s.key = 0
 	}
 
-	diff := lastKey ^ s.key
-	sum := rand.Float64() * subSampleVol
-	for v := 0; v < 5; v++ {
-		if (diff & (1 << v)) > 0 {
-			s.values[v] = rand.Float64() * subSampleVol
-		}
-		sum += s.values[v]
-	}
-
-	return sum
+	// Rows and the white sample add up to [0.0, 1.0], centre it
+	return s.sum + s.rng.Float64()*s.subSampleVol() - 0.5
 }
 
 func (s *PinkNoise) Lock() {
@@ -0,0 +1,81 @@
+package sid
+
+import (
+	"sync"
+)
+
+// Sequence plays the clips one after another, each preceded by its gap of silence, and then ends.
+// A clip is rewound as it comes up, so the same clip can appear more than once.
+type Sequence struct {
+	clips   []*Mp3
+	gaps    []float64 // seconds
+	current int
+	gapLeft float64
+	ended   bool
+	mu      sync.Mutex
+}
+
+func NewSequence(clips []*Mp3, gaps []float64) *Sequence {
+	s := &Sequence{
+		clips: clips,
+		gaps:  gaps,
+	}
+	s.rewind()
+	return s
+}
+
+func (s *Sequence) HasEnded() bool {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.ended
+}
+
+func (s *Sequence) Reset() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.rewind()
+}
+
+func (s *Sequence) rewind() {
+	s.current = 0
+	s.ended = len(s.clips) == 0
+	if !s.ended {
+		s.gapLeft = s.gaps[0]
+		s.clips[0].Reset()
+	}
+}
+
+func (s *Sequence) Gen(sampleRate float64) float64 {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.ended {
+		return 0.0
+	}
+	if s.gapLeft > 0.0 {
+		s.gapLeft -= 1.0 / sampleRate
+		return 0.0
+	}
+
+	smp := s.clips[s.current].Gen(sampleRate)
+	if s.clips[s.current].HasEnded() {
+		s.current++
+		if s.current >= len(s.clips) {
+			s.ended = true
+		} else {
+			s.gapLeft = s.gaps[s.current]
+			s.clips[s.current].Reset()
+		}
+	}
+	return smp
+}
+
+func (s *Sequence) Lock() {
+	s.mu.Lock()
+}
+
+func (s *Sequence) Unlock() {
+	s.mu.Unlock()
+}
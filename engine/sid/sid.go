@@ -18,6 +18,12 @@ type SignalSource interface {
 	Unlock()
 }
 
+// Clip is a SignalSource that plays through once.
+type Clip interface {
+	SignalSource
+	HasEnded() bool
+}
+
 type Sid struct {
 	channels map[string]*Channel
 	// This mutex is only used when changing channel sources and volumes
@@ -45,6 +51,12 @@ func (s *Sid) SetVolume(chname string, volume float64) {
 	s.mu.Unlock()
 }
 
+func (s *Sid) SetPan(chname string, pan float64) {
+	s.mu.Lock()
+	s.channels[chname].pan = math.Max(-1.0, math.Min(pan, 1.0))
+	s.mu.Unlock()
+}
+
 func (s *Sid) IsPaused(chname string) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -86,54 +98,8 @@ func (s *Sid) Start(sampleRate float64) {
 
 	var err error
 	//var channels []Channel
-	s.mainStream, err = portaudio.OpenDefaultStream(0, 1, sampleRate, 0, func(out []float32) {
-		s.mu.Lock()
-		defer s.mu.Unlock()
-
-		for o := range out {
-			out[o] = 0.0
-
-			for _, ch := range s.channels {
-				if ch.src != nil {
-					if ch.fadeDirection == SID_FADE_IN && ch.fadeCurrent < ch.fadeSamples {
-						ch.fadeCurrent++
-					} else if ch.fadeDirection == SID_FADE_OUT && ch.fadeCurrent > 0 {
-						ch.fadeCurrent--
-					} else if ch.paused {
-						continue
-					}
-
-					// Internally locked
-					out[o] += float32(ch.src.Gen(sampleRate)) * (float32(ch.fadeCurrent) / float32(ch.fadeSamples)) * float32(ch.volume)
-				}
-			}
-
-			max := math.Abs(float64(out[o]))
-			if max > 1.0 {
-				s.movingMax = max
-				fmt.Printf("movingMax=%f (clip)\n", s.movingMax)
-			} else {
-				s.movingMax -= s.movingMax / 256.0
-				s.movingMax += max / 256.0
-			}
-
-			if s.movingMax > 1.0 {
-				out[o] /= float32(s.movingMax)
-			}
-
-			if out[o] > 1.0 {
-				if o == 0 {
-					fmt.Printf("clipping\n")
-				}
-				out[o] = 1.0
-			}
-			if out[o] < -1.0 {
-				if o == 0 {
-					fmt.Printf("clipping\n")
-				}
-				out[o] = -1.0
-			}
-		}
+	s.mainStream, err = portaudio.OpenDefaultStream(0, 2, sampleRate, 0, func(out [][]float32) {
+		s.MixStereo(out[0], out[1], sampleRate)
 	})
 	if err != nil {
 		fmt.Printf("Error opening default stream: %s\n", err)
@@ -147,6 +113,84 @@ func (s *Sid) Start(sampleRate float64) {
 	}
 }
 
+// Mix renders the next len(out) samples of all channels in mono, ignoring the pan. Start drives
+// MixStereo from the audio callback, but both can be called directly to render offline.
+func (s *Sid) Mix(out []float32, sampleRate float64) {
+	s.mix(out, nil, sampleRate)
+}
+
+// MixStereo renders the next len(left) samples of all channels, panned.
+func (s *Sid) MixStereo(left, right []float32, sampleRate float64) {
+	s.mix(left, right, sampleRate)
+}
+
+// Panning only ever turns the far side down, so centred channels play as loud as in mono.
+func (s *Sid) mix(left, right []float32, sampleRate float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for o := range left {
+		l := float32(0.0)
+		r := float32(0.0)
+
+		for _, ch := range s.channels {
+			if ch.src != nil {
+				if ch.fadeDirection == SID_FADE_IN && ch.fadeCurrent < ch.fadeSamples {
+					ch.fadeCurrent++
+				} else if ch.fadeDirection == SID_FADE_OUT && ch.fadeCurrent > 0 {
+					ch.fadeCurrent--
+				} else if ch.paused {
+					continue
+				}
+
+				// Internally locked
+				smp := float32(ch.src.Gen(sampleRate)) * (float32(ch.fadeCurrent) / float32(ch.fadeSamples)) * float32(ch.volume)
+				if right == nil {
+					l += smp
+					continue
+				}
+				l += smp * float32(math.Min(1.0, 1.0-ch.pan))
+				r += smp * float32(math.Min(1.0, 1.0+ch.pan))
+			}
+		}
+
+		max := math.Max(math.Abs(float64(l)), math.Abs(float64(r)))
+		if max > 1.0 {
+			s.movingMax = max
+			fmt.Printf("movingMax=%f (clip)\n", s.movingMax)
+		} else {
+			s.movingMax -= s.movingMax / 256.0
+			s.movingMax += max / 256.0
+		}
+
+		if s.movingMax > 1.0 {
+			l /= float32(s.movingMax)
+			r /= float32(s.movingMax)
+		}
+
+		left[o] = clip(l, o)
+		if right != nil {
+			right[o] = clip(r, o)
+		}
+	}
+}
+
+func clip(smp float32, o int) float32 {
+	if smp > 1.0 {
+		if o == 0 {
+			fmt.Printf("clipping\n")
+		}
+		return 1.0
+	}
+	if smp < -1.0 {
+		if o == 0 {
+			fmt.Printf("clipping\n")
+		}
+		return -1.0
+	}
+	return smp
+}
+
 func (s *Sid) Close() {
 	for i := 0; i <= 20; i++ {
 		for chname, ch := range s.channels {
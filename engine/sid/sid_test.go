@@ -0,0 +1,27 @@
+package sid
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Mixing a buffer the size portaudio asks for, with more and more channels.
+func BenchmarkSidMix(b *testing.B) {
+	buf := make([]float32, 512)
+	for _, n := range []int{1, 4, 16, 64} {
+		chs := make(map[string]*Channel)
+		for c := 0; c < n; c++ {
+			chs[fmt.Sprintf("ch%d", c)] = NewChannel(1.0 / float64(n))
+		}
+		s := New(chs)
+		for name := range chs {
+			s.SetSource(name, NewSine(440.0, 4))
+		}
+
+		b.Run(fmt.Sprintf("channels=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				s.Mix(buf, testSampleRate)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package sid
+
+import (
+	"sync"
+)
+
+// Stems plays a set of synchronised loops and fades the individual layers in and out.
+// Gain changes are held back until the next bar boundary so transitions stay on the beat.
+type Stems struct {
+	layers      []*stemLayer
+	bpm         float64
+	beatsPerBar int
+	fadeBars    float64
+	position    int64
+	mu          sync.Mutex
+}
+
+type stemLayer struct {
+	src     SignalSource
+	gain    float64
+	target  float64
+	pending float64
+	step    float64
+}
+
+func NewStems(srcs []SignalSource, bpm float64, beatsPerBar int) *Stems {
+	s := &Stems{
+		bpm:         bpm,
+		beatsPerBar: beatsPerBar,
+		fadeBars:    1.0,
+	}
+	for _, src := range srcs {
+		s.layers = append(s.layers, &stemLayer{src: src})
+	}
+	return s
+}
+
+// SetLayer requests a new gain for the layer, applied from the next bar onwards.
+func (s *Stems) SetLayer(layer int, gain float64) {
+	s.Lock()
+	s.layers[layer].pending = gain
+	s.Unlock()
+}
+
+func (s *Stems) Layers() int {
+	return len(s.layers)
+}
+
+func (s *Stems) Reset() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.position = 0
+	for _, l := range s.layers {
+		l.src.Reset()
+		l.gain = l.pending
+		l.target = l.pending
+		l.step = 0.0
+	}
+}
+
+func (s *Stems) Gen(sampleRate float64) float64 {
+	s.Lock()
+	defer s.Unlock()
+
+	samplesPerBar := int64(60.0 / s.bpm * float64(s.beatsPerBar) * sampleRate)
+	if samplesPerBar > 0 && s.position%samplesPerBar == 0 {
+		fadeSamples := s.fadeBars * float64(samplesPerBar)
+		for _, l := range s.layers {
+			l.target = l.pending
+			l.step = (l.target - l.gain) / fadeSamples
+		}
+	}
+	s.position++
+
+	smp := 0.0
+	for _, l := range s.layers {
+		if (l.step > 0.0 && l.gain < l.target) || (l.step < 0.0 && l.gain > l.target) {
+			l.gain += l.step
+		} else {
+			l.gain = l.target
+		}
+
+		// All layers must advance, even silent ones, to stay in sync.
+		smp += l.src.Gen(sampleRate) * l.gain
+	}
+	return smp
+}
+
+func (s *Stems) Lock() {
+	s.mu.Lock()
+}
+
+func (s *Stems) Unlock() {
+	s.mu.Unlock()
+}
@@ -0,0 +1,217 @@
+package sid
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+const (
+	SID_WAVE_SINE     = "sine"
+	SID_WAVE_SQUARE   = "square"
+	SID_WAVE_SAW      = "saw"
+	SID_WAVE_TRIANGLE = "triangle"
+
+	SID_SYNTH_VOICES = 16
+)
+
+// Envelope times are in seconds, sustain is a level [0.0,1.0].
+type Envelope struct {
+	Attack  float64
+	Decay   float64
+	Sustain float64
+	Release float64
+}
+
+type Instrument struct {
+	Wave     string
+	Envelope Envelope
+	Volume   float64
+}
+
+var DefaultInstrument = Instrument{
+	Wave: SID_WAVE_TRIANGLE,
+	Envelope: Envelope{
+		Attack:  0.01,
+		Decay:   0.1,
+		Sustain: 0.6,
+		Release: 0.2,
+	},
+	Volume: 0.5,
+}
+
+type SynthEvent struct {
+	// Seconds on the synth clock
+	At       float64
+	Channel  uint8
+	Key      uint8
+	Velocity uint8
+	On       bool
+}
+
+type synthVoice struct {
+	channel  uint8
+	key      uint8
+	freq     float64
+	velocity float64
+	phase    float64
+	level    float64
+	held     bool
+	started  int64
+}
+
+// Synth is a polyphonic oscillator-plus-envelope instrument, played by scheduling note events
+// on its own sample clock.
+type Synth struct {
+	instruments [16]Instrument
+	voices      []*synthVoice
+	events      []SynthEvent
+	samples     int64
+	sampleRate  float64
+	mu          sync.Mutex
+}
+
+func NewSynth() *Synth {
+	s := &Synth{
+		sampleRate: 44100.0,
+	}
+	for ch := range s.instruments {
+		s.instruments[ch] = DefaultInstrument
+	}
+	return s
+}
+
+func (s *Synth) SetInstrument(channel uint8, inst Instrument) {
+	s.Lock()
+	s.instruments[channel] = inst
+	s.Unlock()
+}
+
+// Clock returns the synth time in seconds, as counted in generated samples.
+func (s *Synth) Clock() float64 {
+	s.Lock()
+	defer s.Unlock()
+
+	return float64(s.samples) / s.sampleRate
+}
+
+// Schedule queues events to be played once the synth clock reaches them.
+func (s *Synth) Schedule(evs []SynthEvent) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.events = append(s.events, evs...)
+	sort.SliceStable(s.events, func(i, j int) bool {
+		return s.events[i].At < s.events[j].At
+	})
+}
+
+// Silence drops pending events and releases all playing notes.
+func (s *Synth) Silence() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.events = s.events[:0]
+	for _, v := range s.voices {
+		v.held = false
+	}
+}
+
+func (s *Synth) Reset() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.events = s.events[:0]
+	s.voices = s.voices[:0]
+	s.samples = 0
+}
+
+func (s *Synth) Gen(sampleRate float64) float64 {
+	s.Lock()
+	defer s.Unlock()
+
+	s.sampleRate = sampleRate
+	now := float64(s.samples) / sampleRate
+	for len(s.events) > 0 && s.events[0].At <= now {
+		s.apply(s.events[0])
+		s.events = s.events[1:]
+	}
+	s.samples++
+
+	smp := 0.0
+	alive := s.voices[:0]
+	for _, v := range s.voices {
+		inst := s.instruments[v.channel]
+		env := inst.Envelope
+		if v.held {
+			age := float64(s.samples-v.started) / sampleRate
+			if age < env.Attack {
+				v.level += (1.0 - v.level) / math.Max(1.0, (env.Attack-age)*sampleRate)
+			} else if age < env.Attack+env.Decay {
+				v.level -= (v.level - env.Sustain) / math.Max(1.0, (env.Attack+env.Decay-age)*sampleRate)
+			} else {
+				v.level = env.Sustain
+			}
+		} else {
+			v.level -= 1.0 / math.Max(1.0, env.Release*sampleRate)
+			if v.level <= 0.0 {
+				continue
+			}
+		}
+
+		smp += wave(inst.Wave, v.phase) * v.level * v.velocity * inst.Volume
+		_, v.phase = math.Modf(v.phase + v.freq/sampleRate)
+		alive = append(alive, v)
+	}
+	s.voices = alive
+
+	return smp
+}
+
+func (s *Synth) apply(ev SynthEvent) {
+	for _, v := range s.voices {
+		if v.held && v.channel == ev.Channel && v.key == ev.Key {
+			v.held = false
+		}
+	}
+	if !ev.On || ev.Velocity == 0 {
+		return
+	}
+
+	if len(s.voices) >= SID_SYNTH_VOICES {
+		// Steal the oldest voice
+		s.voices = s.voices[1:]
+	}
+	s.voices = append(s.voices, &synthVoice{
+		channel:  ev.Channel,
+		key:      ev.Key,
+		freq:     440.0 * math.Pow(2.0, (float64(ev.Key)-69.0)/12.0),
+		velocity: float64(ev.Velocity) / 127.0,
+		held:     true,
+		started:  s.samples,
+	})
+}
+
+func wave(shape string, phase float64) float64 {
+	switch shape {
+	case SID_WAVE_SQUARE:
+		if phase < 0.5 {
+			return 0.5
+		}
+		return -0.5
+	case SID_WAVE_SAW:
+		return phase - 0.5
+	case SID_WAVE_TRIANGLE:
+		return (1.0 - math.Abs(phase-0.5)*4.0) / 2.0
+	default:
+		return math.Sin(2*math.Pi*phase) / 2.0
+	}
+}
+
+func (s *Synth) Lock() {
+	s.mu.Lock()
+}
+
+func (s *Synth) Unlock() {
+	s.mu.Unlock()
+}
@@ -21,9 +21,9 @@ func (s *VolumeAdjust) Gen(sampleRate float64) float64 {
 }
 
 func (s *VolumeAdjust) Lock() {
-	s.Lock()
+	s.signal.Lock()
 }
 
 func (s *VolumeAdjust) Unlock() {
-	s.Unlock()
+	s.signal.Unlock()
 }
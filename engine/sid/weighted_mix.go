@@ -0,0 +1,52 @@
+package sid
+
+import (
+	"sync"
+)
+
+// WeightedMix is a Mix whose inputs and their weights can be swapped while playing.
+type WeightedMix struct {
+	signals []SignalSource
+	weights []float64
+	mu      sync.Mutex
+}
+
+func NewWeightedMix() *WeightedMix {
+	return &WeightedMix{}
+}
+
+// SetInputs replaces all inputs. Each signal must appear only once, or it would be read twice per sample.
+func (s *WeightedMix) SetInputs(signals []SignalSource, weights []float64) {
+	s.mu.Lock()
+	s.signals = append(s.signals[:0], signals...)
+	s.weights = append(s.weights[:0], weights...)
+	s.mu.Unlock()
+}
+
+func (s *WeightedMix) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, sig := range s.signals {
+		sig.Reset()
+	}
+}
+
+func (s *WeightedMix) Gen(sampleRate float64) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	smp := 0.0
+	for i, sig := range s.signals {
+		smp += sig.Gen(sampleRate) * s.weights[i]
+	}
+	return smp
+}
+
+func (s *WeightedMix) Lock() {
+	s.mu.Lock()
+}
+
+func (s *WeightedMix) Unlock() {
+	s.mu.Unlock()
+}
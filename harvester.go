@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"math/rand"
 	"time"
 
@@ -18,26 +19,45 @@ const (
 	RESPONSE_AWAITING_INSTRUCTIONS = "awaitingInstructions"
 	RESPONSE_BLOWING_OFF           = "blowingOff"
 	RESPONSE_READY_TO_GO           = "readyToGo"
+
+	HRV_CALLSIGN = "27"
+	// Percent of the hopper filled per second
+	HRV_SPICE_RATE = 0.5
 )
 
 type Harvester struct {
+	freq                  float64
+	callsign              string
+	spiceLoad             float64
 	radioState            string
 	radioSnippets         []*sid.Mp3
 	shuffledSnippets      []*sid.Mp3
 	radioFiller           *sid.Mp3
 	currentSnippet        int
+	current               sid.Clip
+	composer              *radioComposer
+	reportNext            bool
 	noise                 sid.SignalSource
 	defaultIntervalLength time.Duration
 	intervalLength        time.Duration
 	prePostLength         time.Duration
 	sectionStart          time.Time
-	responseMap           map[string]string
-	responseCurrent       string
+	responseMap           map[RadioMessage]string
+	response              sid.Clip
+	responsePending       sid.Clip
+	responsePendingLine   *transcriptLine
+	line                  *transcriptLine
+	transcript            *Transcript
 	responseSnippets      map[string]*sid.Mp3
+	static                sid.Clip
 }
 
-func NewHarvester() *Harvester {
+func NewHarvester(freq float64, composer *radioComposer, t *Transcript) *Harvester {
 	h := &Harvester{
+		freq:                  freq,
+		transcript:            t,
+		callsign:              HRV_CALLSIGN,
+		composer:              composer,
 		radioState:            HRV_RADIO_STATE_INTERVAL,
 		defaultIntervalLength: time.Second * 5.0,
 		intervalLength:        time.Second * 5.0,
@@ -45,27 +65,28 @@ func NewHarvester() *Harvester {
 		sectionStart:          time.Now(),
 		radioFiller:           sid.NewMp3("assets/modem.mp3", true),
 		radioSnippets: []*sid.Mp3{
-			sid.NewMp3("assets/hrv_snippets/snippet-01.mp3", false),
-			sid.NewMp3("assets/hrv_snippets/snippet-02.mp3", false),
-			sid.NewMp3("assets/hrv_snippets/snippet-03.mp3", false),
-			sid.NewMp3("assets/hrv_snippets/snippet-04.mp3", false),
-			sid.NewMp3("assets/hrv_snippets/snippet-05.mp3", false),
-			sid.NewMp3("assets/hrv_snippets/snippet-06.mp3", false),
-			sid.NewMp3("assets/hrv_snippets/snippet-07.mp3", false),
+			t.NewMp3("assets/hrv_snippets/snippet-01.mp3", false),
+			t.NewMp3("assets/hrv_snippets/snippet-02.mp3", false),
+			t.NewMp3("assets/hrv_snippets/snippet-03.mp3", false),
+			t.NewMp3("assets/hrv_snippets/snippet-04.mp3", false),
+			t.NewMp3("assets/hrv_snippets/snippet-05.mp3", false),
+			t.NewMp3("assets/hrv_snippets/snippet-06.mp3", false),
+			t.NewMp3("assets/hrv_snippets/snippet-07.mp3", false),
 		},
-		noise: sid.NewVolumeAdjust(&sid.RandomNoise{}, 0.1),
-		responseMap: map[string]string{
-			"cutTheEngines": RESPONSE_ENGINES_CUT,
-			"comingIn":      RESPONSE_AWAITING_INSTRUCTIONS,
-			"blowTheSpice":  RESPONSE_BLOWING_OFF,
-			"getReady":      RESPONSE_READY_TO_GO,
+		noise: sid.NewVolumeAdjust(sid.NewRandomNoise(time.Now().UnixNano()), 0.1),
+		responseMap: map[RadioMessage]string{
+			TRANSMIT_CUT_THE_ENGINES: RESPONSE_ENGINES_CUT,
+			TRANSMIT_COMING_IN:       RESPONSE_AWAITING_INSTRUCTIONS,
+			TRANSMIT_BLOW_THE_SPICE:  RESPONSE_BLOWING_OFF,
+			TRANSMIT_GET_READY:       RESPONSE_READY_TO_GO,
 		},
 		responseSnippets: map[string]*sid.Mp3{
-			RESPONSE_AWAITING_INSTRUCTIONS: sid.NewMp3("assets/resp_snippets/snippet-01.mp3", false),
-			RESPONSE_BLOWING_OFF:           sid.NewMp3("assets/resp_snippets/snippet-02.mp3", false),
-			RESPONSE_ENGINES_CUT:           sid.NewMp3("assets/resp_snippets/snippet-03.mp3", false),
-			RESPONSE_READY_TO_GO:           sid.NewMp3("assets/resp_snippets/snippet-04.mp3", false),
+			RESPONSE_AWAITING_INSTRUCTIONS: t.NewMp3("assets/resp_snippets/snippet-01.mp3", false),
+			RESPONSE_BLOWING_OFF:           t.NewMp3("assets/resp_snippets/snippet-02.mp3", false),
+			RESPONSE_ENGINES_CUT:           t.NewMp3("assets/resp_snippets/snippet-03.mp3", false),
+			RESPONSE_READY_TO_GO:           t.NewMp3("assets/resp_snippets/snippet-04.mp3", false),
 		},
+		static: sid.NewBurst(sid.NewVolumeAdjust(sid.NewCrackleNoise(3000.0, time.Now().UnixNano()), 0.5), 0.8),
 	}
 	h.shuffledSnippets = make([]*sid.Mp3, len(h.radioSnippets))
 	h.ReshuffleRadioSnippets()
@@ -79,8 +100,50 @@ func (s *Harvester) ReshuffleRadioSnippets() {
 	})
 }
 
+func (s *Harvester) Step(dt float64) {
+	s.spiceLoad += HRV_SPICE_RATE * dt
+	if s.spiceLoad >= 100.0 {
+		// Hopper emptied into the carryall
+		s.spiceLoad = 0.0
+	}
+}
+
+// Chatter alternates between the canned snippets and status reports composed from the live state,
+// if the words for them are recorded.
+func (s *Harvester) nextChatter() sid.Clip {
+	if s.reportNext {
+		s.reportNext = false
+		report, text := s.composer.Compose("status", map[string]string{
+			"callsign": s.callsign,
+			"load":     fmt.Sprintf("%d", int(s.spiceLoad)),
+			"range":    fmt.Sprintf("%d", int(p1.carryall.position.Sub(s.GetLocation()).Len()/100.0)),
+		})
+		if report != nil {
+			s.line = &transcriptLine{Speaker: "Harvester", Text: text}
+			return report
+		}
+	}
+	s.reportNext = true
+
+	snippet := s.shuffledSnippets[s.currentSnippet]
+	s.line = s.transcript.Line(snippet)
+	s.currentSnippet++
+	if s.currentSnippet >= len(s.radioSnippets) {
+		s.ReshuffleRadioSnippets()
+		s.currentSnippet = 0
+	}
+	return snippet
+}
+
+func (s *Harvester) Speaking() *transcriptLine {
+	if s.radioState != HRV_RADIO_STATE_SNIPPET {
+		return nil
+	}
+	return s.line
+}
+
 func (s *Harvester) GetFreq() float64 {
-	return 3550.0
+	return s.freq
 }
 
 func (s *Harvester) GetLocation() pixel.Vec {
@@ -93,29 +156,26 @@ func (s *Harvester) GetFiller() sid.SignalSource {
 
 func (s *Harvester) GetSignal() sid.SignalSource {
 	if s.radioState == HRV_RADIO_STATE_SNIPPET {
-		if s.responseCurrent != "" {
-			if s.responseSnippets[s.responseCurrent].HasEnded() {
-				s.responseCurrent = ""
+		if s.response != nil {
+			response := s.response
+			if response.HasEnded() {
+				s.response = nil
 				s.sectionStart = time.Now()
 				s.radioState = HRV_RADIO_STATE_POST
 			}
-			return s.responseSnippets[s.responseCurrent]
+			return response
 		} else {
-			if s.shuffledSnippets[s.currentSnippet].HasEnded() {
-				s.currentSnippet++
-				if s.currentSnippet >= len(s.radioSnippets) {
-					s.ReshuffleRadioSnippets()
-					s.currentSnippet = 0
-				}
+			if s.current.HasEnded() {
 				s.sectionStart = time.Now()
 				s.radioState = HRV_RADIO_STATE_POST
 			}
-			return s.shuffledSnippets[s.currentSnippet]
+			return s.current
 		}
 	} else if s.radioState == HRV_RADIO_STATE_POST {
 		if time.Since(s.sectionStart) > s.prePostLength {
 			s.sectionStart = time.Now()
 			s.radioState = HRV_RADIO_STATE_INTERVAL
+			s.respond()
 		}
 		return s.noise
 	} else if s.radioState == HRV_RADIO_STATE_INTERVAL {
@@ -129,8 +189,9 @@ func (s *Harvester) GetSignal() sid.SignalSource {
 		if time.Since(s.sectionStart) > s.prePostLength {
 			s.sectionStart = time.Now()
 			s.radioState = HRV_RADIO_STATE_SNIPPET
-			if s.responseCurrent == "" {
-				s.shuffledSnippets[s.currentSnippet].Reset()
+			if s.response == nil {
+				s.current = s.nextChatter()
+				s.current.Reset()
 			}
 		}
 		return s.noise
@@ -139,12 +200,40 @@ func (s *Harvester) GetSignal() sid.SignalSource {
 	return nil
 }
 
-func (s *Harvester) Transmit(msg string) {
+// Transmit answers what was heard, asks to say again what wasn't clear and ignores the rest.
+// The answer waits for the harvester to finish what it's saying.
+func (s *Harvester) Transmit(msg RadioMessage, delivery int) {
+	switch delivery {
+	case DELIVERY_HEARD:
+		s.responsePending = s.responseSnippets[s.responseMap[msg]]
+		s.responsePendingLine = s.transcript.Line(s.responsePending)
+	case DELIVERY_GARBLED:
+		sayAgain, text := s.composer.Compose("sayAgain", map[string]string{"callsign": s.callsign})
+		if sayAgain == nil {
+			// Until "say again" is recorded, a crackle of static that can't be taken for an answer
+			s.responsePending = s.static
+			s.responsePendingLine = &transcriptLine{Speaker: "Harvester", Text: "SAY AGAIN"}
+			break
+		}
+		s.responsePending = sayAgain
+		s.responsePendingLine = &transcriptLine{Speaker: "Harvester", Text: text}
+	default:
+		return
+	}
+
 	s.intervalLength = time.Second * 10.0
-	if s.radioState == HRV_RADIO_STATE_INTERVAL {
-		s.responseCurrent = s.responseMap[msg]
-		s.responseSnippets[s.responseCurrent].Reset()
-		s.sectionStart = time.Now()
-		s.radioState = HRV_RADIO_STATE_PRE
+	s.respond()
+}
+
+func (s *Harvester) respond() {
+	if s.radioState != HRV_RADIO_STATE_INTERVAL || s.responsePending == nil {
+		return
 	}
+	s.response = s.responsePending
+	s.line = s.responsePendingLine
+	s.responsePending = nil
+	s.responsePendingLine = nil
+	s.response.Reset()
+	s.sectionStart = time.Now()
+	s.radioState = HRV_RADIO_STATE_PRE
 }
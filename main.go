@@ -15,9 +15,9 @@ import (
 	"github.com/faiface/pixel/pixelgl"
 	"github.com/faiface/pixel/text"
 	engine "github.com/mateusz/carryall/engine/entities"
+	"github.com/mateusz/carryall/engine/input"
 	"github.com/mateusz/carryall/engine/sid"
 	"github.com/mateusz/carryall/piksele"
-	"gitlab.com/gomidi/midi/writer"
 	"golang.org/x/image/colornames"
 	"golang.org/x/image/font/basicfont"
 )
@@ -34,7 +34,7 @@ var (
 	p1             player
 	gameWorld      piksele.World
 	gameEntities   engine.Entities
-	mc             midiController
+	mc             *midiController
 	startTime      time.Time
 	mainBackground background
 	mainStarfield  starfield
@@ -43,6 +43,14 @@ var (
 	engineSound    *sid.Vibrato
 	whoosh         *sid.PinkNoise
 	radio          *Radio
+	music          *Music
+	midiPlayer     *MidiPlayer
+	actions        *input.Actions
+	output         *input.Output
+	surface        *input.OscSurface
+	profiles       []*input.Profile
+	virtual        *VirtualController
+	calibration    *calibrationScreen
 )
 
 func main() {
@@ -77,27 +85,48 @@ func main() {
 	carryall.velocity = pixel.Vec{X: 0.0, Y: 0.5}
 	gameEntities = gameEntities.Add(&carryall)
 
-	radio = NewRadio()
+	transcript := NewTranscript(fmt.Sprintf("%s/assets/transcript.json", workDir))
+	radio = NewRadio(fmt.Sprintf("%s/assets/radio.json", workDir), transcript)
+	radio.SetTerrain(gameWorld.BuildTerrain())
+	radio.SetWorldWidth(float64(gameWorld.PixelWidth()))
 	gameEntities = gameEntities.Add(radio)
 
-	gameEntities = gameEntities.Add(NewHarvester())
+	for _, b := range LoadBeacons(&gameWorld, radio) {
+		gameEntities = gameEntities.Add(b)
+	}
+
+	harvester := NewHarvester(radio.SourceFreq("harvester"), newRadioComposer(fmt.Sprintf("%s/assets/radio_words.json", workDir)), transcript)
+	gameEntities = gameEntities.Add(harvester)
+
+	music = NewMusic(fmt.Sprintf("%s/assets/music.json", workDir))
+	music.harvester = harvester
+	gameEntities = gameEntities.Add(music)
+
+	midiPlayer = NewMidiPlayer()
+	gameEntities = gameEntities.Add(midiPlayer)
 
 	p1.position = pixel.Vec{X: 256.0, Y: 256.0}
 	p1.carryall = &carryall
 	p1.radio = radio
 
-	mc, err = newMidiController()
-	defer mc.close()
+	actions = input.NewActions(fmt.Sprintf("%s/assets/bindings.json", workDir))
+	profiles = input.LoadProfiles(fmt.Sprintf("%s/assets/controllers", workDir))
+	actions.SetSurfaceProfile(input.ProfileFor(profiles, ""))
 
-	mc.writer.SetChannel(engine.MIDI_CHAN_LEFT)
-	writer.NoteOn(mc.writer, engine.MIDI_KEY_PLAY, 0x7F)
-	writer.NoteOff(mc.writer, engine.MIDI_KEY_PLAY)
-	mc.writer.SetChannel(engine.MIDI_CHAN_RIGHT)
-	writer.NoteOn(mc.writer, engine.MIDI_KEY_SYNC, 0x7F)
-	writer.NoteOff(mc.writer, engine.MIDI_KEY_SYNC)
-	mc.writer.SetChannel(engine.MIDI_CHAN_MIDDLE)
-	writer.NoteOn(mc.writer, engine.MIDI_KEY_LED_SHOW, 0x7F)
-	writer.NoteOff(mc.writer, engine.MIDI_KEY_LED_SHOW)
+	mc = newMidiController(fmt.Sprintf("%s/assets/midi.json", workDir))
+	defer mc.close()
+	output = input.NewOutput(mc.writer, nil)
+	surface = input.NewOscSurface(fmt.Sprintf("%s/assets/osc.json", workDir))
+	defer surface.Close()
+	output.SetSurface(surface)
+	virtual = NewVirtualController(mc.queue, output)
+	calibration = newCalibrationScreen()
+
+	// Light show on the controller
+	err = midiPlayer.Play(fmt.Sprintf("%s/assets/intro.mid", workDir), false, "Hercules DJControl Starlight")
+	if err != nil {
+		fmt.Printf("Error playing the light show: %s\n", err)
+	}
 
 	mainBackground = newBackogrund(16.0, []stripe{
 		{pos: -20, colour: makeColourful(colornames.Black)},
@@ -117,10 +146,18 @@ func main() {
 	for chName, ch := range p1.radio.GetChannels() {
 		chmap[chName] = ch
 	}
+	for chName, ch := range music.GetChannels() {
+		chmap[chName] = ch
+	}
+	for chName, ch := range midiPlayer.GetChannels() {
+		chmap[chName] = ch
+	}
 
 	audio = sid.New(chmap)
 	carryall.SetupChannels(audio)
 	radio.SetupChannels(audio)
+	music.SetupChannels(audio)
+	midiPlayer.SetupChannels(audio)
 
 	audio.Start(44100.0)
 
@@ -194,6 +231,10 @@ func run() {
 	staticHud := imdraw.New(nil)
 	staticHud.Color = colornames.Black
 	readings := text.New(pixel.ZV, text.NewAtlas(basicfont.Face7x13, text.ASCII))
+	rdfNeedle := imdraw.New(nil)
+	comms := text.New(pixel.ZV, text.NewAtlas(basicfont.Face7x13, text.ASCII))
+	subtitle := text.New(pixel.ZV, text.NewAtlas(basicfont.Face7x13, text.ASCII))
+	controllerStatus := text.New(pixel.ZV, text.NewAtlas(basicfont.Face7x13, text.ASCII))
 
 	prof, _ := os.Create("cpuprof.prof")
 	defer prof.Close()
@@ -220,9 +261,17 @@ func run() {
 			p1.carryall.position.X -= mapCanvas.Bounds().W()
 		}
 
-		gameEntities.Input(win, cam1)
-		gameEntities.MidiInput(mc.queue)
-		gameEntities.MidiOutput(mc.writer)
+		if mc.hasChanged() {
+			useController()
+		}
+		virtual.Input(win, dt)
+		actions.Update(win, mc.queue, dt)
+		surface.Poll(actions)
+		calibration.Input(win, actions)
+		gameEntities.Input(actions)
+		output.Begin()
+		gameEntities.MidiOutput(output)
+		output.Flush(time.Now())
 		gameEntities.Step(dt)
 		gameEntities.MakeNoise(audio)
 		radio.SetLocation(p1.carryall.position)
@@ -236,10 +285,40 @@ func run() {
 		p1hud.Clear(color.RGBA{A: 0.0})
 
 		readings.Clear()
-		fmt.Fprintf(readings, "h=%.0fm\n%.0fkm/h\n%.1fg\n%.2fatm", p1.position.Y, p1.carryall.velocity.Len(), p1.carryall.accelerationStress, p1.carryall.atmoPressure)
+		fmt.Fprintf(readings, "h=%.0fm\n%.0fkm/h\n%.1fg\n%.2fatm\n%s\n%s", p1.position.Y, p1.carryall.velocity.Len(), p1.carryall.accelerationStress, p1.carryall.atmoPressure, radio.Readout(), radio.MessageStatus())
 		readings.Draw(p1hud, pixel.IM.Moved(pixel.Vec{
 			X: 10.0,
-			Y: 45.0,
+			Y: 71.0,
+		}))
+
+		drawRdfNeedle(rdfNeedle, radio, pixel.Vec{X: 160.0, Y: 40.0}, 30.0)
+		rdfNeedle.Draw(p1hud)
+
+		comms.Clear()
+		fmt.Fprint(comms, radio.CommsLog())
+		comms.Draw(p1hud, pixel.IM.Moved(pixel.Vec{
+			X: 10.0,
+			Y: monH - 20.0,
+		}))
+
+		controllerStatus.Clear()
+		if _, ok := mc.connected(); !ok {
+			fmt.Fprint(controllerStatus, "NO CONTROLLER")
+			controllerStatus.Draw(p1hud, pixel.IM.Moved(pixel.Vec{
+				X: monW - controllerStatus.Bounds().W() - 10.0,
+				Y: monH - 20.0,
+			}))
+		}
+
+		virtual.Draw(p1hud, pixel.Vec{X: monW - VIRTUAL_WIDTH - 10.0, Y: 10.0})
+		calibration.Draw(p1hud, actions, pixel.Vec{X: monW/2.0 - 300.0, Y: monH/2.0 + 100.0})
+
+		subtitle.Clear()
+		line := radio.Subtitle()
+		fmt.Fprint(subtitle, line)
+		subtitle.Draw(p1hud, pixel.IM.Scaled(pixel.ZV, 2.0).Moved(pixel.Vec{
+			X: (monW - subtitle.BoundsOf(line).W()*2.0) / 2.0,
+			Y: 60.0,
 		}))
 
 		avgVelocity = p1.carryall.avgVelocity.average()
@@ -332,3 +411,50 @@ func run() {
 		v.streamer.Close()
 	}
 }
+
+// Points the bindings and the LEDs at whichever controller is plugged in now.
+func useController() {
+	name, ok := mc.connected()
+	var profile *input.Profile
+	if ok {
+		profile = input.ProfileFor(profiles, name)
+		if profile == nil {
+			fmt.Printf("No controller profile for %s\n", name)
+		} else {
+			fmt.Printf("Using controller profile %s for %s\n", profile.Name, name)
+		}
+	}
+	// Light shows only go to the model they were recorded for, not one that fell back on its profile
+	if ok && profile != nil && profile.Matches(name) {
+		midiPlayer.SetController(profile)
+	} else {
+		midiPlayer.SetController(nil)
+	}
+	// Only a real controller has anything to calibrate
+	calibration.SetProfile(profile)
+	if profile == nil {
+		// The virtual controller stands in, with or without a controller nobody made a profile for
+		profile = input.ProfileFor(profiles, "")
+	}
+	actions.SetProfile(profile)
+	output.SetProfile(profile)
+	virtual.SetProfile(profile, actions.Faders())
+}
+
+// Dial with a needle pointing at the tuned source. The needle grows with signal strength.
+func drawRdfNeedle(imd *imdraw.IMDraw, r *Radio, centre pixel.Vec, radius float64) {
+	imd.Clear()
+
+	imd.Color = colornames.Black
+	imd.Push(centre)
+	imd.Circle(radius, 1.0)
+
+	angle, strength, ok := r.Bearing()
+	if !ok {
+		return
+	}
+
+	imd.Color = colornames.Red
+	imd.Push(centre, centre.Add(pixel.Unit(angle).Scaled(radius*(0.2+0.8*strength))))
+	imd.Line(2.0)
+}
@@ -1,9 +1,15 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/mateusz/carryall/engine/input"
 	"gitlab.com/gomidi/midi"
 	"gitlab.com/gomidi/midi/reader"
 	"gitlab.com/gomidi/midi/writer"
@@ -12,91 +18,236 @@ import (
 	driver "gitlab.com/gomidi/midicatdrv"
 )
 
+// How often to look for the controller being plugged in or pulled out
+const MIDI_RESCAN_INTERVAL = 2 * time.Second
+
+type midiConfig struct {
+	// Regular expression picking the controller's ports by name. The first port if empty
+	Port string `json:"port"`
+	// Ports never to use, by part of the name
+	Ignore []string `json:"ignore"`
+}
+
+// midiController keeps hold of a controller, opening it again if it's unplugged and plugged back in.
+// Without one the queue stays empty and whatever's written to it is dropped.
 type midiController struct {
-	queue  chan midi.Message
-	driver *driver.Driver
-	in     midi.In
-	out    midi.Out
+	queue chan input.MidiEvent
+	// Writes to whichever controller is plugged in
 	writer *writer.Writer
+	driver *driver.Driver
+	config midiConfig
+	port   *regexp.Regexp
+	stop   chan bool
+
+	mu   sync.Mutex
+	in   midi.In
+	out  midi.Out
+	name string
+	// Set when the controller came or went, until the game catches up
+	changed bool
 }
 
-func newMidiController() (midiController, error) {
-	mc := midiController{
-		queue: make(chan midi.Message, 128),
+func newMidiController(configPath string) *midiController {
+	mc := &midiController{
+		queue:   make(chan input.MidiEvent, 128),
+		stop:    make(chan bool),
+		changed: true,
+	}
+	mc.writer = writer.New(mc)
+
+	f, err := os.Open(configPath)
+	if err != nil {
+		fmt.Printf("Error finding midi config: %s\n", err)
+		os.Exit(2)
+	}
+	defer f.Close()
+
+	err = json.NewDecoder(f).Decode(&mc.config)
+	if err != nil {
+		fmt.Printf("Error loading midi config: %s\n", err)
+		os.Exit(2)
+	}
+	mc.port, err = regexp.Compile(mc.config.Port)
+	if err != nil {
+		fmt.Printf("Error loading midi config: %s\n", err)
+		os.Exit(2)
 	}
 
-	var err error
 	mc.driver, err = driver.New()
 	if err != nil {
-		return mc, fmt.Errorf("Error loading midi driver: %s\n", err)
+		fmt.Printf("No controller, can't load midi driver: %s\n", err)
+		return mc
+	}
+
+	err = mc.connect()
+	if err != nil {
+		fmt.Printf("No controller: %s\n", err)
+	}
+	go mc.watch()
+
+	return mc
+}
+
+func (mc *midiController) wants(port fmt.Stringer) bool {
+	for _, ignore := range mc.config.Ignore {
+		if strings.Contains(port.String(), ignore) {
+			return false
+		}
 	}
+	return mc.port.MatchString(port.String())
+}
 
-	midiIns, err := mc.driver.Ins()
+func (mc *midiController) connect() error {
+	ins, err := mc.driver.Ins()
 	if err != nil {
-		return mc, fmt.Errorf("Error getting midi inputs: %s\n", err)
+		return fmt.Errorf("getting midi inputs: %s", err)
+	}
+	var in midi.In
+	for _, i := range ins {
+		if mc.wants(i) {
+			in = i
+			break
+		}
 	}
-	if len(midiIns) == 0 {
-		return mc, fmt.Errorf("Error getting midi inputs: no midi devices found\n")
+	if in == nil {
+		return fmt.Errorf("no midi inputs matching %q found", mc.config.Port)
 	}
-	mc.in = midiIns[0]
 
-	err = mc.in.Open()
+	err = in.Open()
 	if err != nil {
-		return mc, fmt.Errorf("Error opening midi in: %s\n", err)
+		return fmt.Errorf("opening midi in: %s", err)
 	}
 
 	err = reader.New(
 		reader.NoLogger(),
 		reader.Each(func(pos *reader.Position, msg midi.Message) {
-			mc.queue <- msg
+			// Dropped rather than stall the driver, disconnecting waits on this
+			select {
+			case mc.queue <- input.MidiEvent{Msg: msg, At: time.Now()}:
+			default:
+			}
 		}),
-	).ListenTo(mc.in)
+	).ListenTo(in)
 	if err != nil {
-		return mc, fmt.Errorf("Error listening to midi in: %s\n", err)
+		in.Close()
+		return fmt.Errorf("listening to midi in: %s", err)
 	}
 
-	midiOuts, err := mc.driver.Outs()
+	// Some controllers only talk, so carry on without the LEDs
+	var out midi.Out
+	outs, err := mc.driver.Outs()
 	if err != nil {
-		return mc, fmt.Errorf("Error getting midi outputs: %s\n", err)
+		fmt.Printf("Error getting midi outputs: %s\n", err)
 	}
-	if len(midiIns) == 0 {
-		return mc, fmt.Errorf("Error getting midi outputs: no midi devices found\n")
+	for _, o := range outs {
+		if mc.wants(o) {
+			out = o
+			break
+		}
 	}
-	mc.out = midiOuts[0]
-
-	err = mc.out.Open()
-	if err != nil {
-		return mc, fmt.Errorf("Error opening midi out: %s\n", err)
+	if out != nil {
+		err = out.Open()
+		if err != nil {
+			fmt.Printf("Error opening midi out: %s\n", err)
+			out = nil
+		}
 	}
 
-	mc.writer = writer.New(mc.out)
-
-	return mc, nil
-}
+	mc.mu.Lock()
+	mc.in = in
+	mc.out = out
+	mc.name = in.String()
+	mc.changed = true
+	mc.mu.Unlock()
 
-func (mc midiController) sendOn(channel, note, value uint8) {
-	mc.writer.SetChannel(channel)
-	writer.NoteOn(mc.writer, note, value)
+	fmt.Printf("Controller connected: %s\n", in.String())
+	return nil
 }
 
-func (mc midiController) sendOff(channel, note uint8) {
-	mc.writer.SetChannel(channel)
-	writer.NoteOff(mc.writer, note)
-}
+func (mc *midiController) disconnect() {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
 
-func (mc midiController) close() {
 	if mc.in != nil {
-		err := mc.in.StopListening()
-		if err != nil {
-			fmt.Printf("Error stopping midi in: %s\n", err)
-			os.Exit(2)
-		}
+		mc.in.StopListening()
 		mc.in.Close()
+		fmt.Printf("Controller disconnected: %s\n", mc.name)
 	}
 	if mc.out != nil {
 		mc.out.Close()
 	}
-	if mc.driver != nil {
-		mc.driver.Close()
+	mc.in = nil
+	mc.out = nil
+	mc.name = ""
+	mc.changed = true
+}
+
+// watch notices the controller going away and a new one turning up.
+func (mc *midiController) watch() {
+	ticker := time.NewTicker(MIDI_RESCAN_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-mc.stop:
+			return
+		case <-ticker.C:
+		}
+
+		name, ok := mc.connected()
+		if !ok {
+			// Stays quiet until something turns up
+			mc.connect()
+			continue
+		}
+
+		ins, err := mc.driver.Ins()
+		if err != nil {
+			continue
+		}
+		present := false
+		for _, i := range ins {
+			if i.String() == name {
+				present = true
+			}
+		}
+		if !present {
+			mc.disconnect()
+		}
+	}
+}
+
+// connected gives the name of the controller, if there is one.
+func (mc *midiController) connected() (string, bool) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return mc.name, mc.in != nil
+}
+
+// hasChanged is true once after the controller came or went.
+func (mc *midiController) hasChanged() bool {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	changed := mc.changed
+	mc.changed = false
+	return changed
+}
+
+// Write goes to the controller's output, or nowhere.
+func (mc *midiController) Write(b []byte) (int, error) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if mc.out == nil {
+		return len(b), nil
+	}
+	return mc.out.Write(b)
+}
+
+func (mc *midiController) close() {
+	if mc.driver == nil {
+		return
 	}
+	close(mc.stop)
+	mc.disconnect()
+	mc.driver.Close()
 }
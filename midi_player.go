@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mateusz/carryall/engine/input"
+	"github.com/mateusz/carryall/engine/sid"
+	"gitlab.com/gomidi/midi"
+	"gitlab.com/gomidi/midi/midimessage/channel"
+	"gitlab.com/gomidi/midi/reader"
+)
+
+const SID_CHAN_MIDI_SYNTH = "midiSynth"
+
+type midiFileEvent struct {
+	// Seconds from the start of the file
+	at  float64
+	msg midi.Message
+}
+
+// MidiPlayer plays Standard MIDI Files through the synth and/or the controller's MIDI out.
+// Both follow the synth's sample clock, so lights stay in step with the audio.
+type MidiPlayer struct {
+	synth   *sid.Synth
+	events  []midiFileEvent
+	start   float64
+	nextOut int
+	// Profile the file was recorded for, its notes mean nothing on other controllers
+	controller string
+	// Profile of the controller that's plugged in, if there's one made for it
+	plugged string
+}
+
+func NewMidiPlayer() *MidiPlayer {
+	return &MidiPlayer{
+		synth: sid.NewSynth(),
+	}
+}
+
+func loadMidiFile(path string) ([]midiFileEvent, error) {
+	evs := make([]midiFileEvent, 0)
+
+	var rd *reader.Reader
+	rd = reader.New(
+		reader.NoLogger(),
+		reader.Each(func(pos *reader.Position, msg midi.Message) {
+			switch msg.(type) {
+			case channel.NoteOn, channel.NoteOff, channel.ControlChange, channel.ProgramChange, channel.Pitchbend:
+			default:
+				return
+			}
+
+			at := 0.0
+			if t := reader.TimeAt(rd, pos.AbsoluteTicks); t != nil {
+				at = t.Seconds()
+			}
+			evs = append(evs, midiFileEvent{at: at, msg: msg})
+		}),
+	)
+
+	err := reader.ReadSMFFile(rd, path)
+	if err != nil {
+		return nil, fmt.Errorf("reading midi file %s: %s", path, err)
+	}
+
+	// Tracks are read one after another, interleave them
+	sort.SliceStable(evs, func(i, j int) bool {
+		return evs[i].at < evs[j].at
+	})
+
+	return evs, nil
+}
+
+// Play starts the file from the beginning, replacing whatever was playing. It goes to the controller
+// too if it's the one named, empty keeps it off all of them.
+func (s *MidiPlayer) Play(path string, toSynth bool, controller string) error {
+	evs, err := loadMidiFile(path)
+	if err != nil {
+		return err
+	}
+
+	s.synth.Silence()
+	s.events = evs
+	s.start = s.synth.Clock()
+	s.nextOut = 0
+	s.controller = controller
+
+	if !toSynth {
+		return nil
+	}
+
+	synthEvs := make([]sid.SynthEvent, 0, len(evs))
+	for _, ev := range evs {
+		switch m := ev.msg.(type) {
+		case channel.NoteOn:
+			synthEvs = append(synthEvs, sid.SynthEvent{At: s.start + ev.at, Channel: m.Channel(), Key: m.Key(), Velocity: m.Velocity(), On: true})
+		case channel.NoteOff:
+			synthEvs = append(synthEvs, sid.SynthEvent{At: s.start + ev.at, Channel: m.Channel(), Key: m.Key()})
+		}
+	}
+	s.synth.Schedule(synthEvs)
+
+	return nil
+}
+
+// SetController is the profile made for the controller that's plugged in, nil if there's none.
+func (s *MidiPlayer) SetController(p *input.Profile) {
+	s.plugged = ""
+	if p != nil {
+		s.plugged = p.Name
+	}
+}
+
+func (s *MidiPlayer) Stop() {
+	s.synth.Silence()
+	s.nextOut = len(s.events)
+}
+
+func (s *MidiPlayer) IsPlaying() bool {
+	return s.nextOut < len(s.events)
+}
+
+func (s *MidiPlayer) MidiOutput(out *input.Output) {
+	now := s.synth.Clock() - s.start
+	for s.nextOut < len(s.events) && s.events[s.nextOut].at <= now {
+		if s.controller != "" && s.controller == s.plugged {
+			out.Write(s.events[s.nextOut].msg)
+		}
+		s.nextOut++
+	}
+}
+
+func (s *MidiPlayer) GetChannels() map[string]*sid.Channel {
+	return map[string]*sid.Channel{
+		SID_CHAN_MIDI_SYNTH: sid.NewChannel(0.2),
+	}
+}
+
+func (s *MidiPlayer) SetupChannels(onto *sid.Sid) {
+	onto.SetSource(SID_CHAN_MIDI_SYNTH, s.synth)
+}
+
+func (s *MidiPlayer) MakeNoise(onto *sid.Sid) {
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mateusz/carryall/engine/input"
+	"gitlab.com/gomidi/midi"
+	"gitlab.com/gomidi/midi/midimessage/channel"
+	"gitlab.com/gomidi/midi/reader"
+)
+
+const (
+	// A control is learnt once it's been left alone this long
+	LEARN_SETTLE = 700 * time.Millisecond
+	// Relative controls sending more messages a second than this are jog wheels rather than encoders
+	LEARN_JOG_RATE = 30.0
+	// Turns are timed as at least this long, so a single nudge doesn't work out as a blur of messages
+	LEARN_MIN_TURN = 250 * time.Millisecond
+)
+
+// The client:port numbers ALSA tacks on change between plug-ins
+var portNumbers = regexp.MustCompile(`\s+\d+:\d+$`)
+
+var nonAlnum = regexp.MustCompile(`[^a-z0-9]+`)
+
+// learn goes through the controls the game's bindings use, asking for each one to be moved,
+// and writes a controller profile for whatever is plugged in.
+func learn(in midi.In, bindingsPath, profilesDir string) {
+	msgs := make(chan midi.Message, 128)
+	err := reader.New(
+		reader.NoLogger(),
+		reader.Each(func(pos *reader.Position, msg midi.Message) {
+			msgs <- msg
+		}),
+	).ListenTo(in)
+	must(err)
+
+	enter := make(chan bool)
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			enter <- true
+		}
+	}()
+
+	// Controls in the order the bindings first mention them, with the actions they drive
+	var names []string
+	actions := make(map[string][]string)
+	for _, b := range input.LoadBindings(bindingsPath) {
+		if b.Midi == nil || b.Midi.Control == "" {
+			continue
+		}
+		if _, ok := actions[b.Midi.Control]; !ok {
+			names = append(names, b.Midi.Control)
+		}
+		actions[b.Midi.Control] = append(actions[b.Midi.Control], b.Action)
+	}
+
+	port := portNumbers.ReplaceAllString(in.String(), "")
+	profile := input.Profile{
+		Name:     port,
+		Ports:    []string{port},
+		Controls: make(map[string]input.MidiControl),
+		Leds:     make(map[string]input.Led),
+	}
+
+	fmt.Printf("Learning %s. Press buttons, sweep faders all the way and turn wheels both ways. Enter skips.\n", port)
+	for _, name := range names {
+		fmt.Printf("%s (%s): ", name, strings.Join(actions[name], ", "))
+		drain(msgs)
+
+		c, kind, jog, ok := learnControl(msgs, enter)
+		if !ok {
+			fmt.Printf("skipped\n")
+			continue
+		}
+		if kind == "button" && c.CC != nil {
+			fmt.Printf("a button sending CCs, only note buttons work for now, skipped\n")
+			continue
+		}
+
+		profile.Controls[name] = c
+		if c.Note != nil {
+			fmt.Printf("%s, channel %d note %d\n", kind, c.Channel, *c.Note)
+			// Most controllers light a button with its own note
+			profile.Leds[name] = input.Led{Channel: c.Channel, Note: *c.Note}
+		} else {
+			fmt.Printf("%s, channel %d cc %d %s\n", kind, c.Channel, *c.CC, jog)
+			if c.LSB != nil {
+				fmt.Printf("  with the lsb on cc %d\n", *c.LSB)
+			}
+		}
+
+		if jog == "" {
+			continue
+		}
+		if profile.Jog == "" {
+			profile.Jog = jog
+		} else if profile.Jog != jog {
+			fmt.Printf("  wheels disagree on their encoding, keeping %s\n", profile.Jog)
+		}
+	}
+	if profile.Jog == "" {
+		profile.Jog = "twosComplement"
+	}
+
+	data, err := json.MarshalIndent(profile, "", "  ")
+	must(err)
+	path := filepath.Join(profilesDir, strings.Trim(nonAlnum.ReplaceAllString(strings.ToLower(port), "_"), "_")+".json")
+	must(ioutil.WriteFile(path, append(data, '\n'), 0644))
+	fmt.Printf("Wrote %s\n", path)
+}
+
+func drain(msgs chan midi.Message) {
+	for {
+		select {
+		case <-msgs:
+		default:
+			return
+		}
+	}
+}
+
+// learnControl waits for a control to be moved and works out what it is, or gives up on Enter.
+func learnControl(msgs chan midi.Message, enter chan bool) (c input.MidiControl, kind string, jog string, ok bool) {
+	for {
+		select {
+		case <-enter:
+			return c, "", "", false
+		case msg := <-msgs:
+			switch m := msg.(type) {
+			case channel.NoteOn:
+				if m.Velocity() == 0 {
+					continue
+				}
+				note := m.Key()
+				c = input.MidiControl{Channel: m.Channel(), Note: &note}
+				settle(msgs, m.Channel())
+				return c, "button", "", true
+			case channel.ControlChange:
+				start := time.Now()
+				ccs := settle(msgs, m.Channel())
+				ccs[m.Controller()] = append([]uint8{m.Value()}, ccs[m.Controller()]...)
+
+				// 14-bit faders send their LSB 32 CCs above the MSB, and either can come first
+				cc := m.Controller()
+				if cc >= 32 && cc < 64 && len(ccs[cc-32]) > 0 {
+					cc -= 32
+				}
+				c = input.MidiControl{Channel: m.Channel(), CC: &cc}
+				kind, jog = classify(ccs[cc], time.Since(start)-LEARN_SETTLE)
+				c.Relative = kind == "encoder" || kind == "jog"
+				if cc < 32 && len(ccs[cc+32]) > 0 && kind == "fader" {
+					lsb := cc + 32
+					c.LSB = &lsb
+					kind = "14-bit fader"
+				}
+				return c, kind, jog, true
+			}
+		}
+	}
+}
+
+// settle waits until the control is let go, collecting the values of every CC on its channel.
+func settle(msgs chan midi.Message, ch uint8) map[uint8][]uint8 {
+	ccs := make(map[uint8][]uint8)
+	for {
+		select {
+		case msg := <-msgs:
+			cc, ok := msg.(channel.ControlChange)
+			if ok && cc.Channel() == ch {
+				ccs[cc.Controller()] = append(ccs[cc.Controller()], cc.Value())
+			}
+		case <-time.After(LEARN_SETTLE):
+			return ccs
+		}
+	}
+}
+
+// classify tells controls apart by what they send. Relative controls only ever send small ticks
+// either side of zero, while a fader or an absolute wheel moved all the way goes through the middle.
+func classify(values []uint8, took time.Duration) (kind string, jog string) {
+	onOff := true
+	ticks := true
+	wraps := false
+	for i, v := range values {
+		if v != 0 && v != 127 {
+			onOff = false
+		}
+		if v == 0 || v == 64 || (v&0x3F > 15 && v < 113) {
+			ticks = false
+		}
+		if i > 0 && (int(v)-int(values[i-1]) > 64 || int(v)-int(values[i-1]) < -64) {
+			wraps = true
+		}
+	}
+
+	if onOff && len(values) <= 2 {
+		return "button", ""
+	}
+	if !ticks {
+		if wraps {
+			return "jog", "absolute"
+		}
+		return "fader", ""
+	}
+
+	jog = "twosComplement"
+	big := false
+	for _, v := range values {
+		if v > 64 && v < 96 {
+			jog = "signMagnitude"
+		}
+		if v&0x3F > 1 && v != 127 {
+			big = true
+		}
+	}
+
+	if took < LEARN_MIN_TURN {
+		took = LEARN_MIN_TURN
+	}
+	rate := float64(len(values)) / took.Seconds()
+	if big || rate > LEARN_JOG_RATE {
+		return "jog", jog
+	}
+	return "encoder", jog
+}
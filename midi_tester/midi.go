@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"time"
 
@@ -10,8 +11,23 @@ import (
 	"gitlab.com/gomidi/rtmididrv"
 )
 
+var (
+	learnFlag   = flag.Bool("learn", false, "learn a controller profile for the game's bindings")
+	bindingsArg = flag.String("bindings", "assets/bindings.json", "bindings to learn the controls of")
+	profilesArg = flag.String("profiles", "assets/controllers", "directory to write the learnt profile to")
+	oscArg      = flag.String("osc", "", "act as an OSC surface for the game listening on this address, e.g. 127.0.0.1:8000")
+	replyArg    = flag.String("reply", ":9000", "where the OSC surface listens for feedback")
+)
+
 // This example reads from the first input port
 func main() {
+	flag.Parse()
+
+	if *oscArg != "" {
+		oscLoopback(*oscArg, *replyArg)
+		return
+	}
+
 	drv, err := rtmididrv.New()
 	must(err)
 
@@ -35,6 +51,12 @@ func main() {
 	defer in.Close()
 	defer out.Close()
 
+	if *learnFlag {
+		learn(in, *bindingsArg, *profilesArg)
+		must(in.StopListening())
+		return
+	}
+
 	// to disable logging, pass mid.NoLogger() as option
 	rd := reader.New(
 		reader.NoLogger(),
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mateusz/carryall/engine/input"
+)
+
+// oscLoopback pretends to be a tablet surface. Lines like "left.play 1" or "left.jogRim -3" typed in
+// are sent to the game as controls, and all the feedback that comes back is printed.
+func oscLoopback(gameAddr, listenAddr string) {
+	game, err := net.ResolveUDPAddr("udp", gameAddr)
+	must(err)
+	local, err := net.ResolveUDPAddr("udp", listenAddr)
+	must(err)
+	conn, err := net.ListenUDP("udp", local)
+	must(err)
+	defer conn.Close()
+
+	fmt.Printf("Sending to %s, listening on %s\n", game, conn.LocalAddr())
+	fmt.Println("Type a control and a value, e.g. \"left.play 1\", an empty value presses it")
+
+	go func() {
+		packet := make([]byte, 65536)
+		for {
+			n, _, err := conn.ReadFromUDP(packet)
+			if err != nil {
+				return
+			}
+			msgs, err := input.ParseOsc(packet[:n])
+			if err != nil {
+				fmt.Printf("Bad packet: %s\n", err)
+				continue
+			}
+			for _, m := range msgs {
+				fmt.Println(m.Address, m.Args)
+			}
+		}
+	}()
+
+	lines := bufio.NewScanner(os.Stdin)
+	for lines.Scan() {
+		fields := strings.Fields(lines.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		m := input.OscMessage{Address: input.OSC_CONTROL + fields[0]}
+		if len(fields) > 1 {
+			v, err := strconv.ParseFloat(fields[1], 32)
+			if err != nil {
+				fmt.Printf("Not a number: %s\n", fields[1])
+				continue
+			}
+			m.Args = []interface{}{float32(v)}
+		}
+		_, err = conn.WriteToUDP(m.Bytes(), game)
+		must(err)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/mateusz/carryall/engine/sid"
+)
+
+const SID_CHAN_MUSIC = "music"
+
+const (
+	MUSIC_SIGNAL_BASE      = "base"
+	MUSIC_SIGNAL_ALTITUDE  = "altitude"
+	MUSIC_SIGNAL_STRESS    = "stress"
+	MUSIC_SIGNAL_HARVESTER = "harvester"
+	MUSIC_SIGNAL_RADIO     = "radio"
+)
+
+type musicConfig struct {
+	Volume float64    `json:"volume"`
+	Sets   []musicSet `json:"sets"`
+}
+
+type musicSet struct {
+	Name        string      `json:"name"`
+	Bpm         float64     `json:"bpm"`
+	BeatsPerBar int         `json:"beatsPerBar"`
+	Stems       []musicStem `json:"stems"`
+}
+
+// A stem fades in as its signal rises from From to To, up to Gain.
+type musicStem struct {
+	File   string  `json:"file"`
+	Signal string  `json:"signal"`
+	From   float64 `json:"from"`
+	To     float64 `json:"to"`
+	Gain   float64 `json:"gain"`
+}
+
+type Music struct {
+	config    musicConfig
+	set       *musicSet
+	stems     *sid.Stems
+	harvester *Harvester
+	signals   map[string]float64
+}
+
+func NewMusic(path string) *Music {
+	m := &Music{
+		signals: make(map[string]float64),
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Printf("Error finding music config: %s\n", err)
+		os.Exit(2)
+	}
+	defer f.Close()
+
+	err = json.NewDecoder(f).Decode(&m.config)
+	if err != nil {
+		fmt.Printf("Error loading music config: %s\n", err)
+		os.Exit(2)
+	}
+
+	// Layers only change on the bar, without a tempo there's never a bar to change on
+	for _, set := range m.config.Sets {
+		if set.Bpm <= 0.0 || set.BeatsPerBar <= 0 {
+			fmt.Printf("Error loading music config: set %s needs a positive bpm and beatsPerBar\n", set.Name)
+			os.Exit(2)
+		}
+	}
+
+	if len(m.config.Sets) > 0 {
+		m.Play(m.config.Sets[0].Name)
+	}
+
+	return m
+}
+
+// Play switches to the named stem set. The change is picked up by the next MakeNoise.
+func (s *Music) Play(name string) {
+	for i := range s.config.Sets {
+		set := &s.config.Sets[i]
+		if set.Name != name {
+			continue
+		}
+
+		srcs := make([]sid.SignalSource, 0, len(set.Stems))
+		for _, st := range set.Stems {
+			srcs = append(srcs, sid.NewMp3(st.File, true))
+		}
+		s.set = set
+		s.stems = sid.NewStems(srcs, set.Bpm, set.BeatsPerBar)
+		return
+	}
+	fmt.Printf("Unknown music set: %s\n", name)
+}
+
+func (s *Music) Step(dt float64) {
+	s.signals[MUSIC_SIGNAL_BASE] = 1.0
+	s.signals[MUSIC_SIGNAL_ALTITUDE] = clamp01(p1.carryall.position.Y / 2000.0)
+	s.signals[MUSIC_SIGNAL_STRESS] = clamp01(p1.carryall.accelerationStress / 3.8)
+	if s.harvester != nil {
+		dist := p1.carryall.position.Sub(s.harvester.GetLocation()).Len()
+		s.signals[MUSIC_SIGNAL_HARVESTER] = clamp01(1.0 - dist/1000.0)
+	}
+	s.signals[MUSIC_SIGNAL_RADIO] = clamp01(radio.strength)
+}
+
+func (s *Music) GetChannels() map[string]*sid.Channel {
+	return map[string]*sid.Channel{
+		SID_CHAN_MUSIC: sid.NewChannel(s.config.Volume),
+	}
+}
+
+func (s *Music) SetupChannels(onto *sid.Sid) {
+	if s.stems == nil {
+		onto.SetSource(SID_CHAN_MUSIC, &sid.Silence{})
+		return
+	}
+	onto.SetSource(SID_CHAN_MUSIC, s.stems)
+}
+
+func (s *Music) MakeNoise(onto *sid.Sid) {
+	if s.stems == nil {
+		return
+	}
+	onto.SetSource(SID_CHAN_MUSIC, s.stems)
+
+	for i, st := range s.set.Stems {
+		level := 1.0
+		if st.To != st.From {
+			level = clamp01((s.signals[st.Signal] - st.From) / (st.To - st.From))
+		} else if s.signals[st.Signal] < st.From {
+			level = 0.0
+		}
+		s.stems.SetLayer(i, level*st.Gain)
+	}
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0.0, math.Min(1.0, v))
+}
@@ -0,0 +1,24 @@
+package piksele
+
+import (
+	"github.com/faiface/pixel"
+	"github.com/lafriks/go-tiled"
+)
+
+// ObjectsOfType collects the objects of the given type from all the object layers of the map.
+func (w *World) ObjectsOfType(t string) []*tiled.Object {
+	objs := make([]*tiled.Object, 0)
+	for _, og := range w.Tiles.ObjectGroups {
+		for _, o := range og.Objects {
+			if o.Type == t {
+				objs = append(objs, o)
+			}
+		}
+	}
+	return objs
+}
+
+// Convert object coords (pixels, from the top of the map) to world coordinates.
+func (w *World) ObjectToVec(o *tiled.Object) pixel.Vec {
+	return pixel.V(o.X, float64(w.PixelHeight())-o.Y)
+}
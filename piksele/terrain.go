@@ -0,0 +1,76 @@
+package piksele
+
+import (
+	"math"
+
+	"github.com/faiface/pixel"
+)
+
+// Terrain is the skyline formed by tiles with the "terrain" property set, one height per pixel column.
+type Terrain struct {
+	heights []float64
+}
+
+// BuildTerrain scans the opaque pixels of the terrain tiles to find the top of the ground in each column.
+func (w *World) BuildTerrain() *Terrain {
+	t := &Terrain{
+		heights: make([]float64, w.PixelWidth()),
+	}
+
+	for y := 0; y < w.Tiles.Height; y++ {
+		for x := 0; x < w.Tiles.Width; x++ {
+			tt := w.TilesetTileAt(x, y)
+			if tt == nil || !tt.Properties.GetBool("terrain") {
+				continue
+			}
+			spr, ok := w.Sprites.Sprites[tt.ID]
+			if !ok {
+				continue
+			}
+			pd := pixel.PictureDataFromPicture(spr.Picture())
+
+			bottom := w.TileToVec(x, y).Y - float64(w.Tiles.TileHeight)/2.0
+			left := x * w.Tiles.TileWidth
+			for px := 0; px < w.Tiles.TileWidth; px++ {
+				for py := w.Tiles.TileHeight - 1; py >= 0; py-- {
+					if pd.Color(pixel.V(float64(px)+0.5, float64(py)+0.5)).A > 0.0 {
+						t.heights[left+px] = math.Max(t.heights[left+px], bottom+float64(py)+1.0)
+						break
+					}
+				}
+			}
+		}
+	}
+
+	return t
+}
+
+// HeightAt is the top of the terrain at world x, wrapping around the world.
+func (t *Terrain) HeightAt(x float64) float64 {
+	if len(t.heights) == 0 {
+		return 0.0
+	}
+	i := int(math.Floor(x)) % len(t.heights)
+	if i < 0 {
+		i += len(t.heights)
+	}
+	return t.heights[i]
+}
+
+// Obstruction is how many pixels of the straight line between a and b run through the terrain.
+func (t *Terrain) Obstruction(a, b pixel.Vec) float64 {
+	length := b.Sub(a).Len()
+	if length == 0.0 {
+		return 0.0
+	}
+
+	blocked := 0.0
+	steps := int(math.Ceil(length))
+	for i := 0; i <= steps; i++ {
+		p := pixel.Lerp(a, b, float64(i)/float64(steps))
+		if p.Y < t.HeightAt(p.X) {
+			blocked += length / float64(steps)
+		}
+	}
+	return blocked
+}
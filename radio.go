@@ -1,64 +1,190 @@
 package main
 
 import (
+	"fmt"
 	"math"
+	"os"
+	"sort"
+	"time"
 
 	"github.com/faiface/pixel"
-	"github.com/faiface/pixel/pixelgl"
 	engine "github.com/mateusz/carryall/engine/entities"
+	"github.com/mateusz/carryall/engine/input"
 	"github.com/mateusz/carryall/engine/sid"
-	"gitlab.com/gomidi/midi"
-	"gitlab.com/gomidi/midi/midimessage/channel"
+	"github.com/mateusz/carryall/piksele"
 )
 
 const SID_CHAN_RADIO = "radio"
 const SID_CHAN_RADIO_NOISE = "radioNoise"
-const TRANSMIT_CUT_THE_ENGINES = "cutTheEngines"
-const TRANSMIT_COMING_IN = "comingIn"
-const TRANSMIT_GET_READY = "getReady"
-const TRANSMIT_BLOW_THE_SPICE = "blowTheSpice"
+
+const (
+	RADIO_WINDOW_SIZE      = 10.0 //kHz, encode 300 as 0.0, 3000.0 as 4.0
+	RADIO_ATTENUATION_DIST = 1000.0
+	RADIO_BLEED            = 0.15
+	// Height above the terrain that doubles the range
+	RADIO_ALTITUDE_GAIN = 500.0
+	// Pixels of terrain that cut the signal to 1/e
+	RADIO_TERRAIN_DEPTH = 20.0
+	RADIO_ANTENNA       = 4.0
+	RADIO_GROUND_LEVEL  = 167.0
+	// How far to the side the RDF pans a source that's straight left or right
+	RADIO_RDF_PAN = 0.8
+	// Audio shift per kHz of mistuning, voice drifts out of the band-pass at the edge of the window
+	RADIO_DETUNE_HZ_PER_KHZ = 200.0
+	RADIO_CARRIER_LEVEL     = 0.2
+	RADIO_AUDIO_LOW         = 300.0
+	RADIO_AUDIO_HIGH        = 3000.0
+	// kHz per second swept by the seek
+	RADIO_SEEK_RATE = 20.0
+	// Holding a memory pad this long stores the current frequency instead of recalling it
+	RADIO_MEMORY_STORE_HOLD = time.Second
+	RADIO_MEMORY_CHANNELS   = 4
+)
+
+const (
+	RADIO_MODE_USB = iota
+	RADIO_MODE_LSB
+	RADIO_MODE_AM
+)
+
+var radioModeNames = []string{"USB", "LSB", "AM"}
 
 type RadioSource interface {
 	GetFreq() float64
 	GetLocation() pixel.Vec
 	GetSignal() sid.SignalSource
 	GetFiller() sid.SignalSource
-	Transmit(string)
+}
+
+// RadioReceiver is a source with someone listening back, beacons only send.
+type RadioReceiver interface {
+	// Transmit delivers a message from the pilot, with one of the DELIVERY_ outcomes.
+	Transmit(msg RadioMessage, delivery int)
+}
+
+// Memory channel stored on a hot-cue pad.
+type radioMemory struct {
+	band int
+	freq float64
+}
+
+type radioReception struct {
+	source   RadioSource
+	strength float64
+}
+
+// Demodulation chain kept per source, so the filters don't lose their state between frames.
+type radioChain struct {
+	input   *sid.WeightedMix
+	carrier *sid.Sine
+	shift   *sid.FrequencyShift
+	band    *sid.BandPass
+}
+
+func newRadioChain() *radioChain {
+	c := &radioChain{
+		input:   sid.NewWeightedMix(),
+		carrier: sid.NewSine(1000.0, 1),
+	}
+	c.shift = sid.NewFrequencyShift(c.input)
+	c.band = sid.NewBandPass(c.shift, RADIO_AUDIO_LOW, RADIO_AUDIO_HIGH)
+	return c
 }
 
 // Radio sample processing: pitch -15% -> tempo +33% -> phone equalizer (300-3000) -> distortion/leveler (-50 floor, degree 5) -> aplify to -6
 type Radio struct {
+	plan             radioPlan
+	band             int
 	location         pixel.Vec
+	terrain          *piksele.Terrain
+	worldWidth       float64
 	minFreq          float64
 	maxFreq          float64
-	coarseFreq       float64
-	fineFreq         float64
+	coarseFreq       float64 // [0.0, 1.0]
+	fineFreq         float64 // [0.0, 1.0]
 	squelch          float64
 	freq             float64
 	vol              float64
+	strength         float64
+	bearing          float64
+	rdfPan           bool
+	mode             int
+	seekDir          float64 // 1.0 up, -1.0 down, 0.0 not seeking
+	seekSwept        float64
+	seekStopped      time.Time
+	seekDwell        time.Duration
+	memory           map[int]radioMemory
+	memoryPressed    map[int]time.Time
 	sources          []RadioSource
-	transmitCurrent  string
-	transmitSnippets map[string]*sid.Mp3
+	receptions       []radioReception
+	tuned            RadioSource
+	chains           map[RadioSource]*radioChain
+	transmitting     *radioOutgoing
+	transmitSnippets map[RadioMessage]*sid.Mp3
+	outbox           []radioOutgoing
+	delivery         *radioDelivery
+	transcript       *Transcript
+	commsLog         []*commsEntry
+	hearing          *commsEntry
+	clarity          float64
+	logScroll        int
 
-	sine *sid.Sine
+	mix          *sid.WeightedMix
+	whistle      *sid.Sine
+	interference *sid.CrackleNoise
 }
 
-func NewRadio() *Radio {
+func NewRadio(planPath string, t *Transcript) *Radio {
 	r := &Radio{
-		minFreq: 3500.0,
-		maxFreq: 3600.0,
-		sources: make([]RadioSource, 0),
-		freq:    3500.0,
-		transmitSnippets: map[string]*sid.Mp3{
-			TRANSMIT_CUT_THE_ENGINES: sid.NewMp3("assets/carr_snippets/snippet-01.mp3", false),
-			TRANSMIT_COMING_IN:       sid.NewMp3("assets/carr_snippets/snippet-02.mp3", false),
-			TRANSMIT_GET_READY:       sid.NewMp3("assets/carr_snippets/snippet-03.mp3", false),
-			TRANSMIT_BLOW_THE_SPICE:  sid.NewMp3("assets/carr_snippets/snippet-04.mp3", false),
+		plan:          loadRadioPlan(planPath),
+		transcript:    t,
+		sources:       make([]RadioSource, 0),
+		chains:        make(map[RadioSource]*radioChain),
+		memory:        make(map[int]radioMemory),
+		memoryPressed: make(map[int]time.Time),
+		mix:           sid.NewWeightedMix(),
+		whistle:       sid.NewSine(1000.0, 1),
+		interference:  sid.NewCrackleNoise(0.0, time.Now().UnixNano()),
+		transmitSnippets: map[RadioMessage]*sid.Mp3{
+			TRANSMIT_CUT_THE_ENGINES: t.NewMp3("assets/carr_snippets/snippet-01.mp3", false),
+			TRANSMIT_COMING_IN:       t.NewMp3("assets/carr_snippets/snippet-02.mp3", false),
+			TRANSMIT_GET_READY:       t.NewMp3("assets/carr_snippets/snippet-03.mp3", false),
+			TRANSMIT_BLOW_THE_SPICE:  t.NewMp3("assets/carr_snippets/snippet-04.mp3", false),
 		},
 	}
+	r.seekDwell = time.Duration(r.plan.SeekDwell * float64(time.Second))
+	r.SetBand(0)
 	return r
 }
 
+// SetBand switches to the i-th band of the plan, in the band's mode. The knobs keep their place within the band.
+func (s *Radio) SetBand(i int) {
+	b := s.plan.Bands[i]
+	s.stopSeek()
+	s.band = i
+	s.minFreq = b.MinFreq
+	s.maxFreq = b.MaxFreq
+	s.mode = radioModeByName(b.Mode)
+	s.tuneToKnobs()
+}
+
+// Coarse knob covers the whole band, fine knob one 128th of it.
+func (s *Radio) tuneToKnobs() {
+	freqSpan := s.maxFreq - s.minFreq
+	f := s.minFreq + s.coarseFreq*freqSpan + s.fineFreq*(freqSpan/128.0)
+	s.freq = s.plan.Bands[s.band].snap(math.Min(f, s.maxFreq))
+}
+
+// SourceFreq looks up where the named source transmits on the frequency plan.
+func (s *Radio) SourceFreq(name string) float64 {
+	c, ok := s.plan.channel(name)
+	if !ok {
+		fmt.Printf("Radio source missing from the frequency plan: %s\n", name)
+		os.Exit(2)
+	}
+	return c.Freq
+}
+
 func (s *Radio) SetSources(e engine.Entities) {
 	s.sources = make([]RadioSource, 0)
 	for _, ent := range e {
@@ -73,8 +199,102 @@ func (s *Radio) SetLocation(l pixel.Vec) {
 	s.location = l
 }
 
+func (s *Radio) SetTerrain(t *piksele.Terrain) {
+	s.terrain = t
+}
+
+// SetWorldWidth lets the radio hear across the seam where the world wraps around.
+func (s *Radio) SetWorldWidth(w float64) {
+	s.worldWidth = w
+}
+
+// Shortest way from the radio to p, going around the world if that's closer.
+func (s *Radio) offsetTo(p pixel.Vec) pixel.Vec {
+	d := p.Sub(s.location)
+	if s.worldWidth > 0.0 {
+		d.X = math.Mod(d.X, s.worldWidth)
+		if d.X > s.worldWidth/2.0 {
+			d.X -= s.worldWidth
+		} else if d.X < -s.worldWidth/2.0 {
+			d.X += s.worldWidth
+		}
+	}
+	return d
+}
+
+// Bearing gives the direction finder reading for the tuned source: angle in radians
+// (0 = right, Pi/2 = up) and strength [0.0, 1.0]. Not ok if nothing is tuned in.
+func (s *Radio) Bearing() (angle float64, strength float64, ok bool) {
+	if s.tuned == nil {
+		return 0.0, 0.0, false
+	}
+	return s.bearing, s.strength, true
+}
+
+// Seek sweeps the dial up (dir > 0) or down (dir < 0) until it finds a source above the squelch.
+func (s *Radio) Seek(dir float64) {
+	s.seekDir = math.Copysign(1.0, dir)
+	s.seekSwept = 0.0
+	s.seekStopped = time.Time{}
+}
+
+func (s *Radio) stopSeek() {
+	s.seekDir = 0.0
+	s.seekStopped = time.Time{}
+}
+
 func (s *Radio) Step(dt float64) {
-	// nop
+	if s.seekDir == 0.0 {
+		return
+	}
+	if !s.seekStopped.IsZero() {
+		if time.Since(s.seekStopped) < s.seekDwell {
+			return
+		}
+		s.Seek(s.seekDir)
+	}
+
+	freqSpan := s.maxFreq - s.minFreq
+	from := s.freq
+	sweep := math.Min(RADIO_SEEK_RATE*dt, freqSpan-s.seekSwept)
+	s.seekSwept += sweep
+
+	// Closest source ahead of the dial that comes through above the squelch
+	found := false
+	best := 0.0
+	for _, rs := range s.sources {
+		ahead := math.Mod((rs.GetFreq()-from)*s.seekDir+freqSpan, freqSpan)
+		if ahead <= 0.0 || ahead > sweep || (found && ahead >= best) {
+			continue
+		}
+		strength := s.receptionStrength(rs, rs.GetFreq())
+		if strength > 0.0 && strength >= s.squelch {
+			found = true
+			best = ahead
+		}
+	}
+
+	if found {
+		s.freq = s.wrapFreq(from + best*s.seekDir)
+		if s.seekDwell == 0 {
+			s.stopSeek()
+		} else {
+			s.seekStopped = time.Now()
+		}
+		return
+	}
+
+	s.freq = s.wrapFreq(from + sweep*s.seekDir)
+	if s.seekSwept >= freqSpan {
+		// Went all the way round without hearing anything
+		s.stopSeek()
+	}
+}
+
+// Keeps the seek going round the band.
+func (s *Radio) wrapFreq(f float64) float64 {
+	freqSpan := s.maxFreq - s.minFreq
+	return s.minFreq + math.Mod(f-s.minFreq+freqSpan, freqSpan)
 }
 
 func (s *Radio) GetChannels() map[string]*sid.Channel {
@@ -85,123 +305,265 @@ func (s *Radio) GetChannels() map[string]*sid.Channel {
 }
 
 func (s *Radio) SetupChannels(onto *sid.Sid) {
-	onto.SetSource(SID_CHAN_RADIO, &sid.RandomNoise{})
-	onto.SetSource(SID_CHAN_RADIO_NOISE, &sid.RandomNoise{})
+	onto.SetSource(SID_CHAN_RADIO, sid.NewRandomNoise(time.Now().UnixNano()))
+	onto.SetSource(SID_CHAN_RADIO_NOISE, sid.NewRandomNoise(time.Now().UnixNano()+1))
 }
 
-func (s *Radio) MakeNoise(onto *sid.Sid) {
-	// voice is 4kHz, 300-3000
-	// Use "USB" - at carrier freq and up
+// How well a source comes through when tuned to freq at the current distance, [0.0, 1.0].
+func (s *Radio) receptionStrength(rs RadioSource, freq float64) float64 {
+	// Other bands need a different antenna
+	if rs.GetFreq() < s.minFreq || rs.GetFreq() > s.maxFreq {
+		return 0.0
+	}
 
-	windowSize := 10.0 //kHz, encode 300 as 0.0, 3000.0 as 4.0
-	totalAttenuationDist := 1000.0
-	compoundStrength := 0.0
-	maxStrength := 0.0
-	var radioSource RadioSource
+	// "Mis-tune" count in windows
+	windowDist := math.Abs(freq-rs.GetFreq()) / RADIO_WINDOW_SIZE
+	signalStrength := 0.0
+	if windowDist < 1.0 {
+		signalStrength = 1.0 - windowDist
+	} else if windowDist < 2.0 {
+		// Adjacent-channel bleed
+		signalStrength = (2.0 - windowDist) * RADIO_BLEED
+	}
 
-	for _, rs := range s.sources {
-		dist := s.location.Sub(rs.GetLocation()).Len()
+	return signalStrength * s.propagation(rs.GetLocation())
+}
 
-		// "Mis-tune" count in windows
-		windowDist := (s.freq - rs.GetFreq()) / windowSize
-		signalStrength := 0.0
-		if math.Abs(windowDist) >= 1.0 {
-			signalStrength = 0.0
-		} else {
-			signalStrength = 1.0 - math.Abs(windowDist)
-		}
+// How much of a transmission from the given point makes it to the radio, [0.0, 1.0].
+// Altitude pushes the horizon out, terrain in the way soaks the signal up.
+func (s *Radio) propagation(from pixel.Vec) float64 {
+	rx := s.location.Add(pixel.V(0.0, RADIO_ANTENNA))
+	tx := rx.Add(s.offsetTo(from))
 
-		distFade := (totalAttenuationDist - dist) / totalAttenuationDist
-		if distFade < 0.0 {
-			distFade = 0.0
-		}
+	ground := RADIO_GROUND_LEVEL
+	obstruction := 0.0
+	if s.terrain != nil {
+		ground = math.Max(ground, s.terrain.HeightAt(s.location.X))
+		obstruction = s.terrain.Obstruction(rx, tx)
+	}
+	altitude := math.Max(s.location.Y-ground, 0.0)
+	attenuationDist := RADIO_ATTENUATION_DIST * (1.0 + altitude/RADIO_ALTITUDE_GAIN)
+
+	distFade := (attenuationDist - rx.Sub(tx).Len()) / attenuationDist
+	if distFade < 0.0 {
+		distFade = 0.0
+	}
+
+	return math.Sqrt(distFade) * math.Exp(-obstruction/RADIO_TERRAIN_DEPTH)
+}
+
+// Readout shows the dial for the HUD.
+func (s *Radio) Readout() string {
+	r := fmt.Sprintf("%s %.2fkHz %s", s.plan.Bands[s.band].Title, s.freq, radioModeNames[s.mode])
+	if s.rdfPan {
+		r += " RDF"
+	}
+	if s.seekDir > 0.0 {
+		r += " SEEK>"
+	} else if s.seekDir < 0.0 {
+		r += " <SEEK"
+	}
+	return r
+}
+
+func (s *Radio) chainFor(rs RadioSource) *radioChain {
+	c, ok := s.chains[rs]
+	if !ok {
+		c = newRadioChain()
+		s.chains[rs] = c
+	}
+	return c
+}
+
+// Sets up the chain for a source heard at the current tuning. Voice is 300-3000 Hz above the carrier (USB),
+// so tuning below the carrier pitches the voice up, and listening on the wrong sideband flips it.
+// AM carries its own carrier, so the voice keeps its pitch but the carrier whistles against the receiver.
+func (s *Radio) demodulate(chain *radioChain, signal sid.SignalSource, rs RadioSource) {
+	detune := s.freq - rs.GetFreq()
 
-		compoundStrength = signalStrength * math.Sqrt(distFade)
-		if compoundStrength < maxStrength {
+	switch s.mode {
+	case RADIO_MODE_USB:
+		chain.shift.SetShift(-detune * RADIO_DETUNE_HZ_PER_KHZ)
+	case RADIO_MODE_LSB:
+		chain.shift.SetShift(detune * RADIO_DETUNE_HZ_PER_KHZ)
+	case RADIO_MODE_AM:
+		chain.shift.SetShift(0.0)
+		// Zero-beat with the fine knob to get rid of it
+		chain.carrier.SetFreq(math.Abs(detune) * RADIO_DETUNE_HZ_PER_KHZ)
+		chain.input.SetInputs([]sid.SignalSource{signal, chain.carrier}, []float64{1.0, RADIO_CARRIER_LEVEL})
+		return
+	}
+
+	chain.input.SetInputs([]sid.SignalSource{signal}, []float64{1.0})
+}
+
+func (s *Radio) MakeNoise(onto *sid.Sid) {
+
+	s.receptions = s.receptions[:0]
+	s.tuned = nil
+	s.strength = 0.0
+	for _, rs := range s.sources {
+		strength := s.receptionStrength(rs, s.freq)
+		if strength <= 0.0 {
 			continue
-		} else {
-			maxStrength = compoundStrength
 		}
+		s.receptions = append(s.receptions, radioReception{source: rs, strength: strength})
+		if strength > s.strength {
+			s.strength = strength
+			s.tuned = rs
+		}
+	}
+	sort.SliceStable(s.receptions, func(i, j int) bool {
+		return s.receptions[i].strength > s.receptions[j].strength
+	})
 
-		radioSource = rs
+	pan := 0.0
+	if s.tuned != nil {
+		offset := s.offsetTo(s.tuned.GetLocation())
+		s.bearing = offset.Angle()
+		if s.rdfPan && offset.Len() > 0.0 {
+			pan = offset.X / offset.Len() * RADIO_RDF_PAN
+		}
 	}
 
-	if s.transmitCurrent != "" {
-		onto.SetSource(SID_CHAN_RADIO, s.transmitSnippets[s.transmitCurrent])
+	s.nextTransmission()
+	s.listen()
+	if s.transmitting != nil {
+		onto.SetSource(SID_CHAN_RADIO, s.transmitSnippets[s.transmitting.msg])
+		onto.SetPan(SID_CHAN_RADIO, 0.0)
 		onto.SetVolume(SID_CHAN_RADIO, 0.5*s.vol)
 		onto.SetVolume(SID_CHAN_RADIO_NOISE, 0.0)
-		if s.transmitSnippets[s.transmitCurrent].HasEnded() {
-			if compoundStrength > 0.3 && radioSource != nil {
-				radioSource.Transmit(s.transmitCurrent)
-			}
-			s.transmitCurrent = ""
+		if s.transmitSnippets[s.transmitting.msg].HasEnded() {
+			s.deliver()
 		}
 		return
 	}
 
-	if radioSource != nil {
-		if radioSource.GetSignal() != nil {
-			onto.SetSource(SID_CHAN_RADIO, radioSource.GetSignal())
-		} else {
-			onto.SetSource(SID_CHAN_RADIO, radioSource.GetFiller())
-			compoundStrength /= 2.0
+	signals := make([]sid.SignalSource, 0, len(s.receptions)+2)
+	weights := make([]float64, 0, len(s.receptions)+2)
+	total := 0.0
+	for _, rec := range s.receptions {
+		signal := rec.source.GetSignal()
+		weight := rec.strength
+		if signal == nil {
+			signal = rec.source.GetFiller()
+			weight /= 2.0
+		}
+
+		chain := s.chainFor(rec.source)
+		s.demodulate(chain, signal, rec.source)
+
+		signals = append(signals, chain.band)
+		weights = append(weights, weight)
+		total += weight
+	}
+
+	// Two overlapping carriers beat against each other
+	if len(s.receptions) >= 2 {
+		a, b := s.receptions[0], s.receptions[1]
+		overlap := math.Sqrt(a.strength * b.strength)
+		beat := math.Abs(a.source.GetFreq()-b.source.GetFreq()) * 1000.0
+		if beat > 20.0 {
+			s.whistle.SetFreq(math.Min(beat, 4000.0))
+			signals = append(signals, s.whistle)
+			weights = append(weights, overlap*0.3)
 		}
+		s.interference.SetDensity(overlap * 400.0)
+		signals = append(signals, s.interference)
+		weights = append(weights, overlap*0.5)
 	}
 
-	if compoundStrength >= s.squelch {
-		onto.SetVolume(SID_CHAN_RADIO, compoundStrength*0.5*s.vol)
-		onto.SetVolume(SID_CHAN_RADIO_NOISE, (1.0-compoundStrength)*0.01*s.vol)
+	s.mix.SetInputs(signals, weights)
+	onto.SetSource(SID_CHAN_RADIO, s.mix)
+	onto.SetPan(SID_CHAN_RADIO, pan)
+
+	// AM is easier to tune, but wastes power on the carrier and hisses more
+	noise := 0.01
+	if s.mode == RADIO_MODE_AM {
+		noise *= 2.0
+	}
+
+	if s.strength >= s.squelch {
+		onto.SetVolume(SID_CHAN_RADIO, 0.5*s.vol)
+		onto.SetVolume(SID_CHAN_RADIO_NOISE, (1.0-math.Min(total, 1.0))*noise*s.vol)
 	} else {
 		onto.SetVolume(SID_CHAN_RADIO, 0.0)
 		onto.SetVolume(SID_CHAN_RADIO_NOISE, 0.0)
 	}
 }
 
-func (s *Radio) Input(inputSource *pixelgl.Window, referenceFrame pixel.Matrix) {
-
+func (s *Radio) MidiOutput(out *input.Output) {
+	for led, msg := range radioMessagePads {
+		s.padOutput(out, led, msg)
+	}
+	out.Level(METER_SIGNAL, s.strength)
+	out.Display(DISPLAY_RADIO, s.Readout())
 }
 
-func (s *Radio) MidiInput(msgs []midi.Message) {
-	for _, m := range msgs {
-		noff, ok := m.(channel.NoteOff)
-		if ok {
-			if s.transmitCurrent == "" {
-				if noff.Channel() == engine.MIDI_CHAN_HOT_CUE_LEFT && noff.Key() == engine.MIDI_KEY_BANK_1 {
-					s.transmitCurrent = TRANSMIT_COMING_IN
-					s.transmitSnippets[s.transmitCurrent].Reset()
-				}
-				if noff.Channel() == engine.MIDI_CHAN_HOT_CUE_LEFT && noff.Key() == engine.MIDI_KEY_BANK_2 {
-					s.transmitCurrent = TRANSMIT_BLOW_THE_SPICE
-					s.transmitSnippets[s.transmitCurrent].Reset()
-				}
-				if noff.Channel() == engine.MIDI_CHAN_HOT_CUE_LEFT && noff.Key() == engine.MIDI_KEY_BANK_4 {
-					s.transmitCurrent = TRANSMIT_CUT_THE_ENGINES
-					s.transmitSnippets[s.transmitCurrent].Reset()
-				}
-				if noff.Channel() == engine.MIDI_CHAN_HOT_CUE_LEFT && noff.Key() == engine.MIDI_KEY_BANK_3 {
-					s.transmitCurrent = TRANSMIT_GET_READY
-					s.transmitSnippets[s.transmitCurrent].Reset()
-				}
-			}
+func (s *Radio) Input(actions *input.Actions) {
+	if actions.Released(ACTION_RADIO_SEEK_UP) || actions.Released(ACTION_RADIO_SEEK_DOWN) {
+		dir := 1.0
+		if actions.Released(ACTION_RADIO_SEEK_DOWN) {
+			dir = -1.0
+		}
+		// Same button again cancels
+		if s.seekDir == dir {
+			s.stopSeek()
+		} else {
+			s.Seek(dir)
 		}
+	}
 
-		cc, ok := m.(channel.ControlChange)
-		if ok {
-			if cc.Channel() == engine.MIDI_CHAN_MIDDLE && (cc.Controller() == engine.MIDI_CTRL_MSB) {
-				s.vol = float64(cc.Value()) / 128.0
-			}
-			if cc.Channel() == engine.MIDI_CHAN_LEFT && (cc.Controller() == engine.MIDI_CTRL_BANK_SELECT_MSB) {
-				s.coarseFreq = float64(cc.Value())
-			}
-			if cc.Channel() == engine.MIDI_CHAN_LEFT && (cc.Controller() == engine.MIDI_CTRL_BANK_SELECT_LSB) {
-				s.fineFreq = float64(cc.Value())
-			}
-			if cc.Channel() == engine.MIDI_CHAN_LEFT && (cc.Controller() == engine.MIDI_CTRL_BREATH_CONTROL_MSB) {
-				s.squelch = float64(cc.Value()) / 128.0
+	for ch := 1; ch <= RADIO_MEMORY_CHANNELS; ch++ {
+		action := fmt.Sprintf("%s%d", ACTION_RADIO_MEMORY, ch)
+		if actions.Pressed(action) {
+			s.memoryPressed[ch] = time.Now()
+		}
+		if !actions.Released(action) {
+			continue
+		}
+		pressed, ok := s.memoryPressed[ch]
+		if ok && time.Since(pressed) >= RADIO_MEMORY_STORE_HOLD {
+			s.memory[ch] = radioMemory{band: s.band, freq: s.freq}
+		} else if mem, ok := s.memory[ch]; ok {
+			if mem.band != s.band {
+				s.SetBand(mem.band)
 			}
+			s.stopSeek()
+			s.freq = mem.freq
 		}
+		delete(s.memoryPressed, ch)
 	}
 
-	freqSpan := s.maxFreq - s.minFreq
-	s.freq = s.minFreq + s.coarseFreq*(freqSpan/128.0) + s.fineFreq*(freqSpan/(128.0*128.0))
+	if actions.Released(ACTION_RADIO_BAND) {
+		s.SetBand((s.band + 1) % len(s.plan.Bands))
+	}
+	if actions.Released(ACTION_RADIO_MODE) {
+		s.mode = (s.mode + 1) % len(radioModeNames)
+	}
+	if actions.Released(ACTION_RADIO_RDF) {
+		s.rdfPan = !s.rdfPan
+	}
+	for _, msg := range radioMessages {
+		if actions.Released(ACTION_RADIO_TRANSMIT + string(msg)) {
+			s.Send(msg)
+		}
+	}
+
+	if actions.Moved(ACTION_RADIO_VOLUME) {
+		s.vol = actions.Axis(ACTION_RADIO_VOLUME)
+	}
+	if actions.Moved(ACTION_RADIO_SQUELCH) {
+		s.squelch = actions.Axis(ACTION_RADIO_SQUELCH)
+	}
+
+	// The knobs take over from the seek and the memory channels as soon as they're touched
+	if actions.Moved(ACTION_RADIO_TUNE) || actions.Moved(ACTION_RADIO_FINE_TUNE) {
+		s.coarseFreq = actions.Axis(ACTION_RADIO_TUNE)
+		s.fineFreq = actions.Axis(ACTION_RADIO_FINE_TUNE)
+		s.stopSeek()
+		s.tuneToKnobs()
+	}
+
+	s.scrollLog(actions)
 }
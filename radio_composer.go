@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mateusz/carryall/engine/sid"
+)
+
+const (
+	// Silence between words, seconds
+	COMPOSER_WORD_GAP_MIN = 0.04
+	COMPOSER_WORD_GAP_MAX = 0.12
+	// Silence at a comma, seconds
+	COMPOSER_PAUSE_MIN = 0.25
+	COMPOSER_PAUSE_MAX = 0.4
+)
+
+// Numbers are read out digit by digit
+var composerDigits = []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "niner"}
+
+var templateVars = regexp.MustCompile(`<[a-z]+>`)
+
+type composerConfig struct {
+	// Word or phrase to the mp3 holding it
+	Clips map[string]string `json:"clips"`
+	// Templates by message kind, <name> gets replaced with the value passed to Compose
+	Templates map[string][]string `json:"templates"`
+}
+
+// radioComposer strings recorded words and phrases together into transmissions.
+type radioComposer struct {
+	config composerConfig
+	clips  map[string]*sid.Mp3
+	// Longest phrase first, so "spice load" wins over "spice"
+	phrases []string
+}
+
+func newRadioComposer(path string) *radioComposer {
+	c := &radioComposer{
+		clips: make(map[string]*sid.Mp3),
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Printf("Error finding radio words: %s\n", err)
+		os.Exit(2)
+	}
+	defer f.Close()
+
+	err = json.NewDecoder(f).Decode(&c.config)
+	if err != nil {
+		fmt.Printf("Error loading radio words: %s\n", err)
+		os.Exit(2)
+	}
+
+	for phrase, file := range c.config.Clips {
+		if _, err := os.Stat(file); err != nil {
+			fmt.Printf("Error loading radio words: no clip for %s: %s\n", phrase, err)
+			os.Exit(2)
+		}
+		c.clips[phrase] = sid.NewMp3(file, false)
+		c.phrases = append(c.phrases, phrase)
+	}
+	sort.Slice(c.phrases, func(i, j int) bool {
+		return len(strings.Fields(c.phrases[i])) > len(strings.Fields(c.phrases[j]))
+	})
+
+	// Every template has to be sayable, numbers and all
+	for kind, templates := range c.config.Templates {
+		for _, text := range templates {
+			text = templateVars.ReplaceAllString(text, "0")
+			for _, part := range strings.Split(text, ",") {
+				words := c.spell(strings.Fields(strings.ToLower(part)))
+				for j := 0; j < len(words); {
+					_, n := c.match(words[j:])
+					if n == 0 {
+						fmt.Printf("Error loading radio words: no clip for %q in %s template %q\n", words[j], kind, text)
+						os.Exit(2)
+					}
+					j += n
+				}
+			}
+		}
+	}
+
+	return c
+}
+
+// Compose fills in a random template of the given kind and voices it, also returning the text.
+// Nil if there's nothing it can say.
+func (s *radioComposer) Compose(kind string, vars map[string]string) (*sid.Sequence, string) {
+	templates := s.config.Templates[kind]
+	if len(templates) == 0 {
+		return nil, ""
+	}
+	text := templates[rand.Intn(len(templates))]
+	for name, val := range vars {
+		text = strings.ReplaceAll(text, "<"+name+">", val)
+	}
+
+	clips := make([]*sid.Mp3, 0)
+	gaps := make([]float64, 0)
+	for i, part := range strings.Split(text, ",") {
+		words := s.spell(strings.Fields(strings.ToLower(part)))
+		for j := 0; j < len(words); {
+			phrase, n := s.match(words[j:])
+			if n == 0 {
+				fmt.Printf("No radio clip for: %s\n", words[j])
+				j++
+				continue
+			}
+			gap := COMPOSER_WORD_GAP_MIN + rand.Float64()*(COMPOSER_WORD_GAP_MAX-COMPOSER_WORD_GAP_MIN)
+			if j == 0 && i > 0 {
+				gap = COMPOSER_PAUSE_MIN + rand.Float64()*(COMPOSER_PAUSE_MAX-COMPOSER_PAUSE_MIN)
+			}
+			if len(clips) == 0 {
+				gap = 0.0
+			}
+			clips = append(clips, s.clips[phrase])
+			gaps = append(gaps, gap)
+			j += n
+		}
+	}
+
+	if len(clips) == 0 {
+		return nil, ""
+	}
+	return sid.NewSequence(clips, gaps), text
+}
+
+// Turns numbers into digit words.
+func (s *radioComposer) spell(words []string) []string {
+	spelt := make([]string, 0, len(words))
+	for _, w := range words {
+		w = strings.Trim(w, ".")
+		if strings.Trim(w, "0123456789") != "" {
+			spelt = append(spelt, w)
+			continue
+		}
+		for _, d := range w {
+			spelt = append(spelt, composerDigits[d-'0'])
+		}
+	}
+	return spelt
+}
+
+// Longest recorded phrase the words start with, and how many words it covers.
+func (s *radioComposer) match(words []string) (string, int) {
+	for _, p := range s.phrases {
+		pw := strings.Fields(p)
+		if len(pw) > len(words) {
+			continue
+		}
+		if strings.Join(words[:len(pw)], " ") == p {
+			return p, len(pw)
+		}
+	}
+	return "", 0
+}
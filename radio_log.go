@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/mateusz/carryall/engine/input"
+)
+
+const (
+	RADIO_LOG_SIZE  = 50
+	RADIO_LOG_LINES = 5
+)
+
+// RadioSpeaker is a RadioSource that can tell what it's saying right now.
+type RadioSpeaker interface {
+	// Speaking is the line being transmitted, nil between transmissions.
+	Speaking() *transcriptLine
+}
+
+// A line in the comms log, as well as it came through.
+type commsEntry struct {
+	line    *transcriptLine
+	clarity float64
+}
+
+// Follows what's being said on the radio, starting a new log entry for every new line.
+func (s *Radio) listen() {
+	var line *transcriptLine
+	clarity := 0.0
+	if s.transmitting != nil {
+		line = s.transcript.Line(s.transmitSnippets[s.transmitting.msg])
+		clarity = 1.0
+	} else if s.tuned != nil && s.strength >= s.squelch {
+		sp, ok := s.tuned.(RadioSpeaker)
+		if ok {
+			line = sp.Speaking()
+			clarity = s.strength
+		}
+	}
+
+	if line == nil {
+		s.hearing = nil
+		return
+	}
+
+	if s.hearing == nil || s.hearing.line != line {
+		s.hearing = &commsEntry{line: line}
+		s.commsLog = append(s.commsLog, s.hearing)
+		if len(s.commsLog) > RADIO_LOG_SIZE {
+			s.commsLog = s.commsLog[1:]
+		}
+	}
+	s.clarity = clarity
+	// The log keeps the best it was heard
+	s.hearing.clarity = math.Max(s.hearing.clarity, clarity)
+}
+
+// Subtitle is the line being heard right now, garbled by the signal strength.
+func (s *Radio) Subtitle() string {
+	if s.hearing == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s: %s", s.hearing.line.Speaker, garble(s.hearing.line.Text, s.clarity))
+}
+
+// CommsLog shows the last few lines heard, scrolled back by the log actions.
+func (s *Radio) CommsLog() string {
+	end := len(s.commsLog) - s.logScroll
+	start := end - RADIO_LOG_LINES
+	if start < 0 {
+		start = 0
+	}
+
+	lines := make([]string, 0, RADIO_LOG_LINES)
+	for _, e := range s.commsLog[start:end] {
+		lines = append(lines, fmt.Sprintf("%s: %s", e.line.Speaker, garble(e.line.Text, e.clarity)))
+	}
+	if s.logScroll > 0 {
+		lines = append(lines, fmt.Sprintf("(%d more)", s.logScroll))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (s *Radio) scrollLog(actions *input.Actions) {
+	if actions.Pressed(ACTION_RADIO_LOG_OLDER) {
+		s.logScroll++
+	}
+	if actions.Pressed(ACTION_RADIO_LOG_NEWER) {
+		s.logScroll--
+	}
+	if actions.Pressed(ACTION_RADIO_LOG_LAST) {
+		s.logScroll = 0
+	}
+
+	maxScroll := len(s.commsLog) - RADIO_LOG_LINES
+	if s.logScroll > maxScroll {
+		s.logScroll = maxScroll
+	}
+	if s.logScroll < 0 {
+		s.logScroll = 0
+	}
+}
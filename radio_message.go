@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mateusz/carryall/engine/input"
+)
+
+// RadioMessage is something the pilot can say over the radio.
+type RadioMessage string
+
+const (
+	TRANSMIT_CUT_THE_ENGINES RadioMessage = "cutTheEngines"
+	TRANSMIT_COMING_IN       RadioMessage = "comingIn"
+	TRANSMIT_GET_READY       RadioMessage = "getReady"
+	TRANSMIT_BLOW_THE_SPICE  RadioMessage = "blowTheSpice"
+)
+
+// All the messages, in the order they're queued if sent in the same frame.
+var radioMessages = []RadioMessage{
+	TRANSMIT_CUT_THE_ENGINES,
+	TRANSMIT_COMING_IN,
+	TRANSMIT_GET_READY,
+	TRANSMIT_BLOW_THE_SPICE,
+}
+
+// How a transmission came through at the other end.
+const (
+	DELIVERY_HEARD = iota
+	DELIVERY_GARBLED
+	DELIVERY_NOT_HEARD
+)
+
+var deliveryNames = []string{"heard", "say again", "no reply"}
+
+const (
+	// Signal strength needed for a message to be understood, or at least recognised as one
+	RADIO_HEARD_LEVEL   = 0.5
+	RADIO_GARBLED_LEVEL = 0.2
+	RADIO_RETRY_LIMIT   = 3
+	RADIO_RETRY_DELAY   = 4 * time.Second
+	// How long the pad stays lit once the message got through
+	RADIO_DELIVERED_LED = 3 * time.Second
+)
+
+var radioMessageTitles = map[RadioMessage]string{
+	TRANSMIT_CUT_THE_ENGINES: "CUT THE ENGINES",
+	TRANSMIT_COMING_IN:       "COMING IN",
+	TRANSMIT_GET_READY:       "GET READY",
+	TRANSMIT_BLOW_THE_SPICE:  "BLOW THE SPICE",
+}
+
+// Hot-cue pads the messages are sent from, also used to show how they're doing.
+var radioMessagePads = map[string]RadioMessage{
+	LED_TRANSMIT_1: TRANSMIT_COMING_IN,
+	LED_TRANSMIT_2: TRANSMIT_BLOW_THE_SPICE,
+	LED_TRANSMIT_3: TRANSMIT_GET_READY,
+	LED_TRANSMIT_4: TRANSMIT_CUT_THE_ENGINES,
+}
+
+// A message waiting to go out, or to be tried again.
+type radioOutgoing struct {
+	msg      RadioMessage
+	attempts int
+	due      time.Time
+}
+
+// Outcome of the latest transmission, for the HUD.
+type radioDelivery struct {
+	msg      RadioMessage
+	attempts int
+	outcome  int
+	retrying bool
+	at       time.Time
+}
+
+func deliveryFor(strength float64) int {
+	if strength >= RADIO_HEARD_LEVEL {
+		return DELIVERY_HEARD
+	}
+	if strength >= RADIO_GARBLED_LEVEL {
+		return DELIVERY_GARBLED
+	}
+	return DELIVERY_NOT_HEARD
+}
+
+// Send queues the message, unless it's already waiting to go out.
+func (s *Radio) Send(msg RadioMessage) {
+	if s.transmitting != nil && s.transmitting.msg == msg {
+		return
+	}
+	for _, o := range s.outbox {
+		if o.msg == msg {
+			return
+		}
+	}
+	s.outbox = append(s.outbox, radioOutgoing{msg: msg, due: time.Now()})
+}
+
+// Starts on the first message that's due, if the radio isn't busy.
+func (s *Radio) nextTransmission() {
+	if s.transmitting != nil {
+		return
+	}
+	for i, o := range s.outbox {
+		if time.Now().Before(o.due) {
+			continue
+		}
+		s.outbox = append(s.outbox[:i], s.outbox[i+1:]...)
+		o.attempts++
+		s.transmitting = &o
+		s.transmitSnippets[o.msg].Reset()
+		return
+	}
+}
+
+// Hands the finished transmission to whoever is tuned in, and queues a retry if it didn't get through.
+func (s *Radio) deliver() {
+	o := *s.transmitting
+	s.transmitting = nil
+
+	// Nobody hears it unless there's someone on the other end
+	outcome := DELIVERY_NOT_HEARD
+	receiver, ok := s.tuned.(RadioReceiver)
+	if ok {
+		outcome = deliveryFor(s.strength)
+		receiver.Transmit(o.msg, outcome)
+	}
+
+	retrying := outcome != DELIVERY_HEARD && o.attempts < RADIO_RETRY_LIMIT
+	if retrying {
+		o.due = time.Now().Add(RADIO_RETRY_DELAY)
+		s.outbox = append(s.outbox, o)
+	}
+	s.delivery = &radioDelivery{msg: o.msg, attempts: o.attempts, outcome: outcome, retrying: retrying, at: time.Now()}
+}
+
+// MessageStatus describes the latest transmission for the HUD.
+func (s *Radio) MessageStatus() string {
+	if s.transmitting != nil {
+		return fmt.Sprintf("TX %s (%d/%d)", radioMessageTitles[s.transmitting.msg], s.transmitting.attempts, RADIO_RETRY_LIMIT)
+	}
+	if s.delivery == nil {
+		return ""
+	}
+	status := fmt.Sprintf("%s: %s", radioMessageTitles[s.delivery.msg], deliveryNames[s.delivery.outcome])
+	if s.delivery.retrying {
+		status += fmt.Sprintf(", retry %d/%d", s.delivery.attempts+1, RADIO_RETRY_LIMIT)
+	} else if s.delivery.outcome != DELIVERY_HEARD {
+		status += ", gave up"
+	}
+	return status
+}
+
+// Pad blinks while its message goes out, pulses while it waits its turn, and is lit for a while once it got through.
+func (s *Radio) padOutput(out *input.Output, led string, msg RadioMessage) {
+	if s.transmitting != nil && s.transmitting.msg == msg {
+		out.Blink(led, 2.0)
+		return
+	}
+	for _, o := range s.outbox {
+		if o.msg == msg {
+			out.Pulse(led, 1.0)
+			return
+		}
+	}
+	if s.delivery != nil && s.delivery.msg == msg && s.delivery.outcome == DELIVERY_HEARD {
+		out.Light(led, time.Since(s.delivery.at) < RADIO_DELIVERED_LED)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+// Frequency plan - the bands the radio can switch between and where the sources transmit.
+type radioPlan struct {
+	Bands   []radioBand    `json:"bands"`
+	Sources []radioChannel `json:"sources"`
+	// Seconds a seek stays on a source it found before sweeping on, 0 to stay put
+	SeekDwell float64 `json:"seekDwell"`
+}
+
+type radioBand struct {
+	Name    string  `json:"name"`
+	Title   string  `json:"title"`
+	MinFreq float64 `json:"minFreq"`
+	MaxFreq float64 `json:"maxFreq"`
+	Mode    string  `json:"mode"`
+	Step    float64 `json:"step"`
+}
+
+// Where a named source sits on the plan.
+type radioChannel struct {
+	Name string  `json:"name"`
+	Band string  `json:"band"`
+	Freq float64 `json:"freq"`
+}
+
+func loadRadioPlan(path string) radioPlan {
+	var p radioPlan
+
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Printf("Error finding radio plan: %s\n", err)
+		os.Exit(2)
+	}
+	defer f.Close()
+
+	err = json.NewDecoder(f).Decode(&p)
+	if err != nil {
+		fmt.Printf("Error loading radio plan: %s\n", err)
+		os.Exit(2)
+	}
+
+	if p.SeekDwell < 0.0 {
+		fmt.Printf("Error loading radio plan: negative seekDwell\n")
+		os.Exit(2)
+	}
+	if len(p.Bands) == 0 {
+		fmt.Printf("Error loading radio plan: no bands\n")
+		os.Exit(2)
+	}
+	for _, b := range p.Bands {
+		if b.MaxFreq <= b.MinFreq {
+			fmt.Printf("Error loading radio plan: band %s is empty\n", b.Name)
+			os.Exit(2)
+		}
+		if radioModeByName(b.Mode) < 0 {
+			fmt.Printf("Error loading radio plan: band %s has unknown mode %s\n", b.Name, b.Mode)
+			os.Exit(2)
+		}
+	}
+	for _, c := range p.Sources {
+		b := p.band(c.Band)
+		if b < 0 {
+			fmt.Printf("Error loading radio plan: source %s is on unknown band %s\n", c.Name, c.Band)
+			os.Exit(2)
+		}
+		if c.Freq < p.Bands[b].MinFreq || c.Freq > p.Bands[b].MaxFreq {
+			fmt.Printf("Error loading radio plan: source %s is outside band %s\n", c.Name, c.Band)
+			os.Exit(2)
+		}
+	}
+
+	return p
+}
+
+// Index of the named band, -1 if there's no such band.
+func (p radioPlan) band(name string) int {
+	for i, b := range p.Bands {
+		if b.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (p radioPlan) channel(name string) (radioChannel, bool) {
+	for _, c := range p.Sources {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return radioChannel{}, false
+}
+
+// Rounds freq to the band's tuning step.
+func (b radioBand) snap(freq float64) float64 {
+	if b.Step <= 0.0 {
+		return freq
+	}
+	f := b.MinFreq + math.Round((freq-b.MinFreq)/b.Step)*b.Step
+	return math.Min(f, b.MaxFreq)
+}
+
+func radioModeByName(name string) int {
+	for i, n := range radioModeNames {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
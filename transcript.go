@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+
+	"github.com/mateusz/carryall/engine/sid"
+)
+
+// What is said in a recording, and by whom.
+type transcriptLine struct {
+	Speaker string `json:"speaker"`
+	Text    string `json:"text"`
+}
+
+// Transcript maps the radio recordings to their lines, so they can be subtitled and logged.
+type Transcript struct {
+	lines  map[string]*transcriptLine
+	byClip map[sid.Clip]*transcriptLine
+}
+
+func NewTranscript(path string) *Transcript {
+	t := &Transcript{
+		lines:  make(map[string]*transcriptLine),
+		byClip: make(map[sid.Clip]*transcriptLine),
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Printf("Error finding transcript: %s\n", err)
+		os.Exit(2)
+	}
+	defer f.Close()
+
+	err = json.NewDecoder(f).Decode(&t.lines)
+	if err != nil {
+		fmt.Printf("Error loading transcript: %s\n", err)
+		os.Exit(2)
+	}
+
+	return t
+}
+
+// NewMp3 loads the recording and remembers its line.
+func (t *Transcript) NewMp3(path string, loop bool) *sid.Mp3 {
+	m := sid.NewMp3(path, loop)
+	line, ok := t.lines[path]
+	if !ok {
+		fmt.Printf("Missing transcript for: %s\n", path)
+		return m
+	}
+	t.byClip[m] = line
+	return m
+}
+
+// Line is what's said in the clip, nil if it's not in the transcript.
+func (t *Transcript) Line(c sid.Clip) *transcriptLine {
+	return t.byClip[c]
+}
+
+// Blanks out letters as the clarity drops, fully legible at RADIO_HEARD_LEVEL. The same letters
+// go first every time, so the text doesn't flicker while the signal wavers.
+func garble(text string, clarity float64) string {
+	keep := math.Min(clarity/RADIO_HEARD_LEVEL, 1.0)
+	h := fnv.New32a()
+	h.Write([]byte(text))
+	seed := h.Sum32()
+
+	out := []byte(text)
+	for i, c := range out {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			continue
+		}
+		x := seed ^ uint32(i)*2654435761
+		x ^= x >> 13
+		x *= 0x5bd1e995
+		x ^= x >> 15
+		if float64(x%1000)/1000.0 >= keep {
+			out[i] = '.'
+		}
+	}
+	return string(out)
+}
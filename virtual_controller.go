@@ -0,0 +1,373 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/faiface/pixel/text"
+	"github.com/mateusz/carryall/engine/input"
+	"gitlab.com/gomidi/midi"
+	"gitlab.com/gomidi/midi/midimessage/channel"
+	"golang.org/x/image/colornames"
+	"golang.org/x/image/font/basicfont"
+)
+
+const (
+	// Seconds for a held key to take a fader from one end to the other
+	VIRTUAL_FADER_TIME = 1.0
+	// Holding shift moves faders this much slower, for the 14-bit ones
+	VIRTUAL_FINE = 1.0 / 32.0
+	// Jog wheel ticks a second while a key is held
+	VIRTUAL_JOG_RATE = 30.0
+	// Jog wheel ticks per notch of the mouse wheel
+	VIRTUAL_JOG_SCROLL = 4.0
+
+	VIRTUAL_WIDTH  = 410.0
+	VIRTUAL_HEIGHT = 160.0
+)
+
+const (
+	VIRTUAL_BUTTON = iota
+	VIRTUAL_FADER
+	VIRTUAL_JOG
+)
+
+type virtualKeys struct {
+	// One key presses a button, faders and jogs take one for down and one for up
+	keys []pixelgl.Button
+	hint string
+}
+
+var virtualKeyMap = map[string]virtualKeys{
+	"left.jogRim": {[]pixelgl.Button{pixelgl.KeyA, pixelgl.KeyD}, "A D"},
+	"left.rate":   {[]pixelgl.Button{pixelgl.KeyS, pixelgl.KeyW}, "S W"},
+	"left.volume": {[]pixelgl.Button{pixelgl.KeyQ, pixelgl.KeyE}, "Q E"},
+	"left.bass":   {[]pixelgl.Button{pixelgl.KeyF, pixelgl.KeyG}, "F G"},
+	"left.play":   {[]pixelgl.Button{pixelgl.KeySpace}, "_"},
+	"left.sync":   {[]pixelgl.Button{pixelgl.KeyR}, "R"},
+	"left.pfl":    {[]pixelgl.Button{pixelgl.KeyC}, "C"},
+	"left.vinyl":  {[]pixelgl.Button{pixelgl.KeyV}, "V"},
+	"left.pad.1":  {[]pixelgl.Button{pixelgl.Key1}, "1"},
+	"left.pad.2":  {[]pixelgl.Button{pixelgl.Key2}, "2"},
+	"left.pad.3":  {[]pixelgl.Button{pixelgl.Key3}, "3"},
+	"left.pad.4":  {[]pixelgl.Button{pixelgl.Key4}, "4"},
+
+	"right.jogRim": {[]pixelgl.Button{pixelgl.KeyJ, pixelgl.KeyL}, "J L"},
+	"right.rate":   {[]pixelgl.Button{pixelgl.KeyK, pixelgl.KeyI}, "K I"},
+	"right.play":   {[]pixelgl.Button{pixelgl.KeyP}, "P"},
+	"right.sync":   {[]pixelgl.Button{pixelgl.KeyO}, "O"},
+	"right.pfl":    {[]pixelgl.Button{pixelgl.KeyLeftBracket}, "["},
+	"right.vinyl":  {[]pixelgl.Button{pixelgl.KeyRightBracket}, "]"},
+	"right.pad.1":  {[]pixelgl.Button{pixelgl.Key5}, "5"},
+	"right.pad.2":  {[]pixelgl.Button{pixelgl.Key6}, "6"},
+	"right.pad.3":  {[]pixelgl.Button{pixelgl.Key7}, "7"},
+	"right.pad.4":  {[]pixelgl.Button{pixelgl.Key8}, "8"},
+
+	"crossfader": {[]pixelgl.Button{pixelgl.KeyN, pixelgl.KeyM}, "N M"},
+	"master":     {[]pixelgl.Button{pixelgl.KeyMinus, pixelgl.KeyEqual}, "- ="},
+}
+
+type virtualControl struct {
+	name string
+	kind int
+	// Where it sits on the overlay, from its bottom left corner
+	rect       pixel.Rect
+	horizontal bool
+
+	held  bool
+	mouse bool
+	// [0.0, 1.0] for faders, sent as 14 bits
+	value   float64
+	sent    uint16
+	touched bool
+	// Ticks not sent yet, and where an absolute wheel is
+	jogPart float64
+	jogPos  uint8
+	turned  int
+}
+
+// VirtualController stands in for the DJ controller, turning the keyboard and the mouse into the
+// same MIDI a real one would send. Tab shows the decks on screen, lit up like the real LEDs would be.
+type VirtualController struct {
+	queue    chan input.MidiEvent
+	profile  *input.Profile
+	output   *input.Output
+	controls []*virtualControl
+	visible  bool
+	origin   pixel.Vec
+
+	imd    *imdraw.IMDraw
+	labels *text.Text
+}
+
+func NewVirtualController(queue chan input.MidiEvent, output *input.Output) *VirtualController {
+	v := &VirtualController{
+		queue:  queue,
+		output: output,
+		imd:    imdraw.New(nil),
+		labels: text.New(pixel.ZV, text.NewAtlas(basicfont.Face7x13, text.ASCII)),
+	}
+
+	v.controls = append(v.controls, virtualDeck("left", 0.0)...)
+	v.controls = append(v.controls, virtualDeck("right", 240.0)...)
+	v.controls = append(v.controls,
+		&virtualControl{name: "master", kind: VIRTUAL_FADER, rect: pixel.R(194, 60, 206, 140)},
+		&virtualControl{name: "crossfader", kind: VIRTUAL_FADER, rect: pixel.R(175, 10, 235, 22), horizontal: true},
+	)
+
+	return v
+}
+
+func virtualDeck(side string, x float64) []*virtualControl {
+	controls := []*virtualControl{
+		{name: side + ".jogRim", kind: VIRTUAL_JOG, rect: pixel.R(x+10, 60, x+90, 140)},
+		{name: side + ".rate", kind: VIRTUAL_FADER, rect: pixel.R(x+100, 60, x+112, 140)},
+		{name: side + ".volume", kind: VIRTUAL_FADER, rect: pixel.R(x+140, 60, x+152, 140)},
+		{name: side + ".bass", kind: VIRTUAL_FADER, rect: pixel.R(x+10, 145, x+90, 155), horizontal: true},
+	}
+	for i, button := range []string{"vinyl", "sync", "cue", "play", "pfl"} {
+		left := x + 10.0 + float64(i)*32.0
+		controls = append(controls, &virtualControl{name: side + "." + button, kind: VIRTUAL_BUTTON, rect: pixel.R(left, 35, left+28, 52)})
+	}
+	for i := 0; i < 4; i++ {
+		left := x + 10.0 + float64(i)*40.0
+		controls = append(controls, &virtualControl{name: side + ".pad." + strconv.Itoa(i+1), kind: VIRTUAL_BUTTON, rect: pixel.R(left, 5, left+36, 27)})
+	}
+	return controls
+}
+
+// SetProfile picks the notes and CCs to send, so the virtual decks work like the controller that's plugged in.
+// Faders bound to centred actions, like the throttle, start in the middle until they're moved.
+func (v *VirtualController) SetProfile(p *input.Profile, faders []input.Fader) {
+	v.profile = p
+
+	centred := make(map[string]bool)
+	for _, f := range faders {
+		centred[f.Control] = f.Centred
+	}
+	for _, c := range v.controls {
+		if c.kind != VIRTUAL_FADER || c.touched {
+			continue
+		}
+		c.value = 0.0
+		if centred[c.name] {
+			c.value = 0.5
+		}
+		c.sent = uint16(c.value * 16383.0)
+	}
+}
+
+func (v *VirtualController) Input(win *pixelgl.Window, dt float64) {
+	if win.JustPressed(pixelgl.KeyTab) {
+		v.visible = !v.visible
+	}
+
+	mouse := win.MousePosition().Sub(v.origin)
+	for _, c := range v.controls {
+		keys := virtualKeyMap[c.name].keys
+		over := v.visible && c.rect.Contains(mouse)
+
+		switch c.kind {
+		case VIRTUAL_BUTTON:
+			// Only a click on the button itself, not a drag from a fader that strays over it
+			if over && win.JustPressed(pixelgl.MouseButtonLeft) {
+				c.mouse = true
+			}
+			if !win.Pressed(pixelgl.MouseButtonLeft) {
+				c.mouse = false
+			}
+			down := over && c.mouse
+			if len(keys) > 0 && win.Pressed(keys[0]) {
+				down = true
+			}
+			if down != c.held {
+				c.held = down
+				v.button(c)
+			}
+
+		case VIRTUAL_FADER:
+			if over && win.JustPressed(pixelgl.MouseButtonLeft) {
+				c.mouse = true
+			}
+			if !win.Pressed(pixelgl.MouseButtonLeft) {
+				c.mouse = false
+			}
+			if c.mouse {
+				c.touched = true
+				if c.horizontal {
+					c.value = (mouse.X - c.rect.Min.X) / c.rect.W()
+				} else {
+					c.value = (mouse.Y - c.rect.Min.Y) / c.rect.H()
+				}
+			}
+			speed := dt / VIRTUAL_FADER_TIME
+			if win.Pressed(pixelgl.KeyLeftShift) || win.Pressed(pixelgl.KeyRightShift) {
+				speed *= VIRTUAL_FINE
+			}
+			if len(keys) == 2 && win.Pressed(keys[0]) {
+				c.value -= speed
+				c.touched = true
+			}
+			if len(keys) == 2 && win.Pressed(keys[1]) {
+				c.value += speed
+				c.touched = true
+			}
+			c.value = math.Max(0.0, math.Min(1.0, c.value))
+			v.fader(c)
+
+		case VIRTUAL_JOG:
+			if over {
+				c.jogPart += win.MouseScroll().Y * VIRTUAL_JOG_SCROLL
+			}
+			if len(keys) == 2 && win.Pressed(keys[0]) {
+				c.jogPart -= VIRTUAL_JOG_RATE * dt
+			}
+			if len(keys) == 2 && win.Pressed(keys[1]) {
+				c.jogPart += VIRTUAL_JOG_RATE * dt
+			}
+			ticks := int(c.jogPart)
+			if ticks != 0 {
+				c.jogPart -= float64(ticks)
+				v.jog(c, ticks)
+			}
+		}
+	}
+}
+
+func (v *VirtualController) control(c *virtualControl) (input.MidiControl, bool) {
+	if v.profile == nil {
+		return input.MidiControl{}, false
+	}
+	ctl, ok := v.profile.Controls[c.name]
+	return ctl, ok
+}
+
+func (v *VirtualController) button(c *virtualControl) {
+	ctl, ok := v.control(c)
+	if !ok || ctl.Note == nil {
+		return
+	}
+	if c.held {
+		v.send(channel.Channel(ctl.Channel).NoteOn(*ctl.Note, 0x7F))
+	} else {
+		v.send(channel.Channel(ctl.Channel).NoteOff(*ctl.Note))
+	}
+}
+
+// Faders send their MSB when it changes, followed by the LSB if the control has one.
+func (v *VirtualController) fader(c *virtualControl) {
+	value := uint16(c.value * 16383.0)
+	if value == c.sent {
+		return
+	}
+	last := c.sent
+	c.sent = value
+
+	ctl, ok := v.control(c)
+	if !ok || ctl.CC == nil {
+		return
+	}
+	ch := channel.Channel(ctl.Channel)
+	if ctl.LSB == nil {
+		if value>>7 != last>>7 {
+			v.send(ch.ControlChange(*ctl.CC, uint8(value>>7)))
+		}
+		return
+	}
+	v.send(ch.ControlChange(*ctl.CC, uint8(value>>7)))
+	v.send(ch.ControlChange(*ctl.LSB, uint8(value&0x7F)))
+}
+
+func (v *VirtualController) jog(c *virtualControl, ticks int) {
+	c.turned += ticks
+	ctl, ok := v.control(c)
+	if !ok || ctl.CC == nil {
+		return
+	}
+	c.jogPos = v.profile.JogValue(ticks, c.jogPos)
+	v.send(channel.Channel(ctl.Channel).ControlChange(*ctl.CC, c.jogPos))
+}
+
+// Dropped rather than stall the game if nobody's reading
+func (v *VirtualController) send(msg midi.Message) {
+	select {
+	case v.queue <- input.MidiEvent{Msg: msg, At: time.Now()}:
+	default:
+	}
+}
+
+func (v *VirtualController) Draw(t pixel.Target, at pixel.Vec) {
+	v.origin = at
+	if !v.visible {
+		return
+	}
+
+	v.imd.Clear()
+	v.labels.Clear()
+	v.imd.SetMatrix(pixel.IM.Moved(at))
+
+	v.imd.Color = color.RGBA{R: 20, G: 20, B: 20, A: 200}
+	v.imd.Push(pixel.ZV, pixel.V(VIRTUAL_WIDTH, VIRTUAL_HEIGHT))
+	v.imd.Rectangle(0.0)
+
+	for _, c := range v.controls {
+		switch c.kind {
+		case VIRTUAL_BUTTON:
+			v.imd.Color = colornames.Dimgray
+			if c.held {
+				v.imd.Color = colornames.Lightgray
+			}
+			if v.output.Lit(c.name) {
+				v.imd.Color = colornames.Orange
+			}
+			v.imd.Push(c.rect.Min, c.rect.Max)
+			v.imd.Rectangle(0.0)
+
+		case VIRTUAL_FADER:
+			v.imd.Color = colornames.Dimgray
+			v.imd.Push(c.rect.Min, c.rect.Max)
+			v.imd.Rectangle(1.0)
+			v.imd.Color = colornames.Lightgray
+			pos := c.value
+			if c.horizontal {
+				x := c.rect.Min.X + pos*c.rect.W()
+				v.imd.Push(pixel.V(x, c.rect.Min.Y), pixel.V(x, c.rect.Max.Y))
+			} else {
+				y := c.rect.Min.Y + pos*c.rect.H()
+				v.imd.Push(pixel.V(c.rect.Min.X, y), pixel.V(c.rect.Max.X, y))
+			}
+			v.imd.Line(3.0)
+
+		case VIRTUAL_JOG:
+			radius := math.Min(c.rect.W(), c.rect.H()) / 2.0
+			v.imd.Color = colornames.Dimgray
+			v.imd.Push(c.rect.Center())
+			v.imd.Circle(radius, 2.0)
+			// Turns once every 64 ticks
+			angle := math.Pi/2.0 - float64(c.turned)*2.0*math.Pi/64.0
+			v.imd.Color = colornames.Lightgray
+			v.imd.Push(c.rect.Center(), c.rect.Center().Add(pixel.V(math.Cos(angle), math.Sin(angle)).Scaled(radius)))
+			v.imd.Line(2.0)
+		}
+
+		hint := virtualKeyMap[c.name].hint
+		if hint == "" {
+			continue
+		}
+		// Vertical faders are too narrow to write on
+		if c.kind == VIRTUAL_FADER && !c.horizontal {
+			v.labels.Dot = at.Add(pixel.V(c.rect.Min.X, c.rect.Max.Y+3.0))
+		} else {
+			v.labels.Dot = at.Add(pixel.V(c.rect.Min.X+2.0, c.rect.Min.Y+2.0))
+		}
+		v.labels.WriteString(hint)
+	}
+
+	v.imd.Draw(t)
+	v.labels.Draw(t, pixel.IM)
+}